@@ -1,28 +1,66 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"os/exec"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
 )
 
+const (
+	// Long enough to approve a secret manager prompt
+	sessionKeyCommandTimeout = 2 * time.Minute
+
+	// How long a key the API rejected and sessionKeyCommand still prints is
+	// left alone before the command runs again, so a revoked key doesn't
+	// prompt on every poll
+	sessionKeyCommandRetry = time.Hour
+)
+
+// errNoInput means stdin closed before a line was entered, e.g. an empty pipe
+var errNoInput = errors.New("no input: stdin is closed (to pipe a key, use 'login --stdin')")
+
+// errNoSessionKey means an empty line was entered instead of a key
+var errNoSessionKey = errors.New("no session key provided")
+
+// Shared by the login prompts so input one prompt buffered isn't lost to the next
+var stdinScanner = bufio.NewScanner(os.Stdin)
+
 type AuthSession struct {
 	SessionKey     string    `json:"sessionKey"`
 	OrganizationID string    `json:"organizationId,omitempty"`
 	SavedAt        time.Time `json:"savedAt"`
 }
 
+// LoadAuthSession returns the saved session. With sessionKeyCommand set, the
+// key comes from that command instead of the config file.
 func LoadAuthSession() (*AuthSession, error) {
 	config := LoadConfig()
+	if config.SessionKeyCommand != "" {
+		sessionKey, err := commandSessionKey(config.SessionKeyCommand)
+		if err != nil {
+			return nil, err
+		}
+		config.SessionKey = sessionKey
+	}
 	if config.SessionKey == "" {
 		return nil, fmt.Errorf("no session found")
 	}
 
 	savedAt := time.Time{}
-	if config.SavedAt != nil {
+	if config.SavedAt != nil && config.SessionKeyCommand == "" {
+		// A command's key wasn't saved by login, so its age is unknown
 		savedAt = *config.SavedAt
 	}
 
@@ -33,44 +71,185 @@ func LoadAuthSession() (*AuthSession, error) {
 	}, nil
 }
 
+// sessionFlags let a command use a session given on the command line for
+// that run only, instead of the saved one. Nothing given is ever saved.
+type sessionFlags struct {
+	key     *string
+	keyFile *string
+	orgID   *string
+}
+
+// addSessionFlags registers --session-key, --session-key-file and --org-id on fs
+func addSessionFlags(fs *flag.FlagSet) *sessionFlags {
+	return &sessionFlags{
+		key:     fs.String("session-key", "", "Use this session key for this run only (visible in process listings; prefer --session-key-file)"),
+		keyFile: fs.String("session-key-file", "", "Read the session key for this run from `FILE`"),
+		orgID:   fs.String("org-id", "", "Use this organization ID for this run only"),
+	}
+}
+
+// session returns the session given on the command line, or the saved one
+// if no key was given
+func (f *sessionFlags) session() (*AuthSession, error) {
+	if *f.key != "" && *f.keyFile != "" {
+		return nil, fmt.Errorf("use either --session-key or --session-key-file, not both")
+	}
+
+	sessionKey := cleanSessionKey(*f.key)
+	if *f.keyFile != "" {
+		data, err := os.ReadFile(*f.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session key file: %w", err)
+		}
+		if sessionKey = cleanSessionKey(string(data)); sessionKey == "" {
+			return nil, fmt.Errorf("session key file %s is empty", *f.keyFile)
+		}
+	}
+
+	if sessionKey == "" {
+		session, err := LoadAuthSession()
+		if err != nil {
+			return nil, err
+		}
+		if *f.orgID != "" {
+			session.OrganizationID = *f.orgID
+		}
+		return session, nil
+	}
+	return &AuthSession{SessionKey: sessionKey, OrganizationID: *f.orgID}, nil
+}
+
+// The key from sessionKeyCommand, kept in memory only so the command (which
+// may prompt, e.g. for a fingerprint) runs once per process
+var (
+	keyCommandMutex sync.Mutex
+	keyCommandCache string
+
+	// The cached key once a re-run gave it back after a rejection, and when
+	keyCommandRejected   string
+	keyCommandRejectedAt time.Time
+)
+
+// commandSessionKey returns the session key printed by command, running it
+// only if there is no cached key
+func commandSessionKey(command string) (string, error) {
+	keyCommandMutex.Lock()
+	defer keyCommandMutex.Unlock()
+
+	if keyCommandCache != "" {
+		return keyCommandCache, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sessionKeyCommandTimeout)
+	defer cancel()
+	cmd := shellCommand(ctx, command)
+	cmd.Stdin = os.Stdin // Secret managers may prompt on the terminal
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("sessionKeyCommand failed: %w", err)
+	}
+
+	sessionKey := cleanSessionKey(string(out))
+	if sessionKey == "" {
+		return "", fmt.Errorf("sessionKeyCommand printed no session key")
+	}
+	keyCommandCache = sessionKey
+	return sessionKey, nil
+}
+
+// refreshCommandSession re-runs sessionKeyCommand after the key in client
+// was rejected. If that gives a different key, it stores and returns a
+// client using it. If it gives the same key again, or fails, the command
+// isn't re-run for that key until sessionKeyCommandRetry has passed.
+func refreshCommandSession(client *claude.ClaudeUsageClient) (*claude.ClaudeUsageClient, bool) {
+	if currentConfig().SessionKeyCommand == "" {
+		return nil, false
+	}
+
+	keyCommandMutex.Lock()
+	stale := keyCommandCache
+	if stale == keyCommandRejected && time.Since(keyCommandRejectedAt) < sessionKeyCommandRetry {
+		keyCommandMutex.Unlock()
+		return nil, false
+	}
+	keyCommandCache = ""
+	keyCommandMutex.Unlock()
+
+	session, err := LoadAuthSession()
+	if err != nil || session.SessionKey == stale {
+		if err != nil {
+			log.Printf("Warning: Failed to refresh session key: %v\n", err)
+		} else {
+			log.Printf("Warning: sessionKeyCommand still gives the rejected key, not re-running it for %s\n", formatSnoozeDuration(sessionKeyCommandRetry))
+		}
+		keyCommandMutex.Lock()
+		keyCommandCache = stale
+		keyCommandRejected, keyCommandRejectedAt = stale, time.Now()
+		keyCommandMutex.Unlock()
+		return nil, false
+	}
+
+	log.Println("Session key rejected, using a new one from sessionKeyCommand")
+	fresh := createClientFromSession(session)
+	claudeClient.CompareAndSwap(client, fresh)
+	return fresh, true
+}
+
 func SaveAuthSession(session *AuthSession) error {
 	session.SavedAt = time.Now()
 
-	// Read existing config to preserve menuBarIndicator
-	existing := LoadConfig()
-	existing.SessionKey = session.SessionKey
-	existing.OrganizationID = session.OrganizationID
-	existing.SavedAt = &session.SavedAt
-
-	return SaveConfig(existing)
+	// Update the file in place to preserve menuBarIndicator and the rest
+	return UpdateConfig(func(existing *Config) {
+		if existing.SessionKey != session.SessionKey {
+			// Cached account details belong to the old session
+			existing.AccountName = ""
+			existing.AccountEmail = ""
+			existing.AccountPlan = ""
+			existing.AccountCheckedAt = nil
+		}
+		existing.SessionKey = session.SessionKey
+		existing.OrganizationID = session.OrganizationID
+		existing.SavedAt = &session.SavedAt
+	})
 }
 
+// ClearAuthSession removes the session fields, keeping preferences
 func ClearAuthSession() error {
-	// Completely remove the config file for clean uninstall
-	configPath := GetConfigPath()
-	err := os.Remove(configPath)
-	if err != nil && !os.IsNotExist(err) {
-		return err
+	if _, err := os.Stat(GetConfigPath()); os.IsNotExist(err) {
+		return nil
 	}
-	return nil
+	return UpdateConfig(clearSessionFields)
+}
+
+// clearSessionFields empties everything that identifies or signs in to the
+// account
+func clearSessionFields(config *Config) {
+	config.SessionKey = ""
+	config.OrganizationID = ""
+	config.SavedAt = nil
+	config.AccountName = ""
+	config.AccountEmail = ""
+	config.AccountPlan = ""
+	config.AccountCheckedAt = nil
 }
 
 // LoginWithBrowser opens browser and guides user through manual session key extraction
 func LoginWithBrowser() (*AuthSession, error) {
-	fmt.Println("╔════════════════════════════════════════════════════════════╗")
-	fmt.Println("║           Claude Monitor Lite - Authentication            ║")
-	fmt.Println("╚════════════════════════════════════════════════════════════╝")
-	fmt.Println()
+	info("╔════════════════════════════════════════════════════════════╗")
+	info("║           Claude Monitor Lite - Authentication            ║")
+	info("╚════════════════════════════════════════════════════════════╝")
+	info()
 	fmt.Print("Press Enter to open browser...")
-	fmt.Scanln()
+	if _, err := readLine(stdinScanner); err != nil {
+		return nil, err
+	}
 
 	return extractSessionManually()
 }
 
-// extractSessionManually guides user through manual extraction
-func extractSessionManually() (*AuthSession, error) {
-	// Open browser to Claude
-	url := "https://claude.ai"
+// openBrowser opens url in the default browser
+func openBrowser(url string) error {
 	var err error
 
 	switch runtime.GOOS {
@@ -81,11 +260,20 @@ func extractSessionManually() (*AuthSession, error) {
 	case "windows":
 		err = exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
 	default:
-		return nil, fmt.Errorf("unsupported platform")
+		return fmt.Errorf("unsupported platform")
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to open browser: %w", err)
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+	return nil
+}
+
+// extractSessionManually guides user through manual extraction
+func extractSessionManually() (*AuthSession, error) {
+	// Open browser to Claude
+	if err := openBrowser("https://claude.ai"); err != nil {
+		return nil, err
 	}
 
 	fmt.Println()
@@ -97,32 +285,55 @@ func extractSessionManually() (*AuthSession, error) {
 	fmt.Println("  4. Find the 'sessionKey' cookie")
 	fmt.Println("  5. Double-click the Value to select it, then copy (Cmd+C)")
 	fmt.Println()
+	return promptSessionKey()
+}
+
+// promptSessionKey asks for the sessionKey cookie. The caller saves it once
+// it's validated, so a mistyped key never replaces a working one.
+func promptSessionKey() (*AuthSession, error) {
 	fmt.Print("Paste your sessionKey here: ")
 
-	var sessionKey string
-	if _, err := fmt.Scanln(&sessionKey); err != nil {
-		return nil, fmt.Errorf("failed to read session key: %w", err)
+	sessionKey, err := scanSessionKey(stdinScanner)
+	if err != nil {
+		return nil, err
 	}
+	info()
 
-	if sessionKey == "" {
-		return nil, fmt.Errorf("no session key provided")
-	}
+	return &AuthSession{SessionKey: sessionKey}, nil
+}
 
-	// Clean up the session key (remove quotes, whitespace)
+// cleanSessionKey removes surrounding whitespace and quotes from a pasted key
+func cleanSessionKey(sessionKey string) string {
 	sessionKey = strings.TrimSpace(sessionKey)
-	sessionKey = strings.Trim(sessionKey, "\"'")
+	return strings.Trim(sessionKey, "\"'")
+}
 
-	session := &AuthSession{
-		SessionKey: sessionKey,
-	}
+// readSessionKey reads a full line from r, tolerating a missing trailing newline
+func readSessionKey(r io.Reader) (string, error) {
+	return scanSessionKey(bufio.NewScanner(r))
+}
 
-	if err := SaveAuthSession(session); err != nil {
-		return nil, fmt.Errorf("failed to save session: %w", err)
+// scanSessionKey reads the next line from scanner as a session key
+func scanSessionKey(scanner *bufio.Scanner) (string, error) {
+	line, err := readLine(scanner)
+	if err != nil {
+		return "", fmt.Errorf("failed to read session key: %w", err)
 	}
 
-	fmt.Println()
-	fmt.Println("✓ Session saved successfully!")
-	fmt.Println()
+	sessionKey := cleanSessionKey(line)
+	if sessionKey == "" {
+		return "", errNoSessionKey
+	}
+	return sessionKey, nil
+}
 
-	return session, nil
+// readLine reads one full line, spaces and all, returning errNoInput at EOF
+func readLine(scanner *bufio.Scanner) (string, error) {
+	if scanner.Scan() {
+		return scanner.Text(), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errNoInput
 }
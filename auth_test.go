@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadSessionKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"trailing newline", "sk-ant-sid01-abc\n", "sk-ant-sid01-abc", false},
+		{"no trailing newline", "sk-ant-sid01-abc", "sk-ant-sid01-abc", false},
+		{"quoted with whitespace", "  \"sk-ant-sid01-abc\"  \r\n", "sk-ant-sid01-abc", false},
+		{"only first line", "sk-ant-sid01-abc\nextra\n", "sk-ant-sid01-abc", false},
+		{"empty", "", "", true},
+		{"inner space kept", "sk-ant sid01\n", "sk-ant sid01", false},
+		{"blank line", "   \n", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readSessionKey(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readSessionKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("readSessionKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLineEOF(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("first\n"))
+	if got, err := readLine(scanner); err != nil || got != "first" {
+		t.Fatalf("readLine() = %q, %v, want first line", got, err)
+	}
+	if _, err := readLine(scanner); !errors.Is(err, errNoInput) {
+		t.Errorf("readLine() at EOF error = %v, want errNoInput", err)
+	}
+}
+
+func TestSessionFlags(t *testing.T) {
+	t.Setenv(configPathEnv, filepath.Join(t.TempDir(), "config.json"))
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte("sk-ant-sid01-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	emptyFile := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(emptyFile, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantKey string
+		wantOrg string
+		wantErr bool
+	}{
+		{"key flag", []string{"--session-key", "sk-ant-sid01-flag"}, "sk-ant-sid01-flag", "", false},
+		{"key file with org", []string{"--session-key-file", keyFile, "--org-id", "org-1"}, "sk-ant-sid01-file", "org-1", false},
+		{"both given", []string{"--session-key", "k", "--session-key-file", keyFile}, "", "", true},
+		{"empty file", []string{"--session-key-file", emptyFile}, "", "", true},
+		{"no saved session", nil, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			sessionArgs := addSessionFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			session, err := sessionArgs.session()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("session() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (session.SessionKey != tt.wantKey || session.OrganizationID != tt.wantOrg) {
+				t.Errorf("session() = %q/%q, want %q/%q", session.SessionKey, session.OrganizationID, tt.wantKey, tt.wantOrg)
+			}
+		})
+	}
+}
+
+func TestSessionKeyCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	defer func() {
+		keyCommandCache = ""
+		keyCommandRejected, keyCommandRejectedAt = "", time.Time{}
+		claudeClient.Store(nil)
+	}()
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	writeKey := func(key string) {
+		if err := os.WriteFile(keyFile, []byte(key+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeKey("sk-ant-sid01-first")
+
+	command := "cat '" + keyFile + "'"
+	setTestConfig(t, func(c *Config) { c.SessionKeyCommand = command })
+	t.Setenv(configPathEnv, filepath.Join(dir, "config.json"))
+	if err := SaveConfig(Config{SessionKey: "sk-ant-sid01-saved", SessionKeyCommand: command}); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := LoadAuthSession()
+	if err != nil || session.SessionKey != "sk-ant-sid01-first" {
+		t.Fatalf("LoadAuthSession() = %+v, %v, want the command's key", session, err)
+	}
+
+	// Cached: a new key isn't picked up until the old one is rejected
+	writeKey("sk-ant-sid01-second")
+	if session, _ := LoadAuthSession(); session.SessionKey != "sk-ant-sid01-first" {
+		t.Errorf("LoadAuthSession() re-ran the command, got %q", session.SessionKey)
+	}
+
+	client := createClientFromSession(session)
+	claudeClient.Store(client)
+	fresh, ok := refreshCommandSession(client)
+	if !ok || claudeClient.Load() != fresh {
+		t.Fatalf("refreshCommandSession() = %v, want the client replaced", ok)
+	}
+	if session, _ := LoadAuthSession(); session.SessionKey != "sk-ant-sid01-second" {
+		t.Errorf("LoadAuthSession() after refresh = %q, want the new key", session.SessionKey)
+	}
+
+	// The command still gives the rejected key: nothing to retry with
+	if _, ok := refreshCommandSession(fresh); ok {
+		t.Error("refreshCommandSession() with an unchanged key = true, want false")
+	}
+
+	// Nor is the command re-run on the next rejection, until the retry is due
+	writeKey("sk-ant-sid01-third")
+	if _, ok := refreshCommandSession(fresh); ok {
+		t.Error("refreshCommandSession() right after an unchanged key = true, want it to wait")
+	}
+	keyCommandRejectedAt = time.Now().Add(-sessionKeyCommandRetry)
+	if _, ok := refreshCommandSession(fresh); !ok {
+		t.Error("refreshCommandSession() once the retry is due = false, want the new key")
+	}
+}
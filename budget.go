@@ -0,0 +1,108 @@
+// budget.go - Daily cap on fetches (maxDailyRequests)
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const budgetFilePermissions = 0600 // Owner read/write only
+
+// errBudgetExhausted means a scheduled fetch was skipped because today's
+// maxDailyRequests have been used
+var errBudgetExhausted = errors.New("daily request budget used up")
+
+// requestBudget counts the day's fetches. The count is kept in budgetFile so
+// a restart doesn't hand out a fresh budget.
+type requestBudget struct {
+	mu     sync.Mutex
+	Day    string `json:"day"` // Local date, 2006-01-02; the count resets when it changes
+	Count  int    `json:"count"`
+	loaded bool
+}
+
+var fetchBudget = &requestBudget{}
+
+// take counts a fetch on now's day and reports whether it may go ahead: the
+// day's count was below limit, or force is set (Refresh Now, or the first
+// fetch after startup). Returns the count including this fetch.
+func (b *requestBudget) take(now time.Time, limit int, force bool) (int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.load()
+	if day := now.Format("2006-01-02"); b.Day != day {
+		b.Day, b.Count = day, 0
+	}
+	if b.Count >= limit && !force {
+		return b.Count, false
+	}
+	b.Count++
+	b.save()
+	return b.Count, true
+}
+
+// exhausted reports whether now's day has used up limit fetches
+func (b *requestBudget) exhausted(now time.Time, limit int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.load()
+	return limit > 0 && b.Day == now.Format("2006-01-02") && b.Count >= limit
+}
+
+// load reads budgetFile once. A missing or unreadable file starts from zero.
+func (b *requestBudget) load() {
+	if b.loaded {
+		return
+	}
+	b.loaded = true
+	data, err := os.ReadFile(budgetFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, b); err != nil {
+		log.Printf("Warning: Ignoring unreadable request count: %v\n", err)
+	}
+}
+
+func (b *requestBudget) save() {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(budgetFile, data, budgetFilePermissions); err != nil {
+		log.Printf("Warning: Failed to save request count: %v\n", err)
+	}
+}
+
+// spendRequest takes a fetch from today's budget, reporting whether it may
+// go ahead. Scheduled fetches stop at maxDailyRequests until local midnight;
+// forced ones go over it with a warning.
+func spendRequest(force bool) bool {
+	limit := currentConfig().MaxDailyRequests
+	if limit <= 0 {
+		return true
+	}
+
+	count, ok := fetchBudget.take(time.Now(), limit, force)
+	switch {
+	case !ok:
+		return false
+	case count == limit:
+		log.Printf("Used the daily budget of %d requests, polling paused until midnight\n", limit)
+	case count > limit:
+		log.Printf("Warning: Went over the daily budget for a forced fetch (%d of %d requests)\n", count, limit)
+	}
+	return true
+}
+
+// Helper function to check whether scheduled fetches are paused for the day
+func budgetExhausted(now time.Time) bool {
+	return fetchBudget.exhausted(now, currentConfig().MaxDailyRequests)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRequestBudget(t *testing.T) {
+	saved := budgetFile
+	t.Cleanup(func() { budgetFile = saved })
+	budgetFile = filepath.Join(t.TempDir(), "requests.json")
+	morning := time.Date(2025, 6, 12, 9, 0, 0, 0, time.Local)
+
+	budget := &requestBudget{}
+	for want := 1; want <= 3; want++ {
+		if count, ok := budget.take(morning, 3, false); !ok || count != want {
+			t.Fatalf("take() #%d = %d, %v, want %d, true", want, count, ok, want)
+		}
+	}
+	if !budget.exhausted(morning, 3) {
+		t.Error("exhausted() after 3 of 3 = false, want true")
+	}
+	if _, ok := budget.take(morning, 3, false); ok {
+		t.Error("take() over budget = true, want false")
+	}
+	if count, ok := budget.take(morning, 3, true); !ok || count != 4 {
+		t.Errorf("take() forced = %d, %v, want 4, true", count, ok)
+	}
+
+	// A restart picks up the saved count
+	restarted := &requestBudget{}
+	if !restarted.exhausted(morning.Add(time.Hour), 3) {
+		t.Error("exhausted() after a restart = false, want the saved count")
+	}
+	if restarted.exhausted(morning, 10) {
+		t.Error("exhausted() with a higher limit = true, want false")
+	}
+
+	// Local midnight starts a new day
+	tomorrow := morning.AddDate(0, 0, 1).Add(-9 * time.Hour)
+	if restarted.exhausted(tomorrow, 3) {
+		t.Error("exhausted() the next day = true, want false")
+	}
+	if count, ok := restarted.take(tomorrow, 3, false); !ok || count != 1 {
+		t.Errorf("take() the next day = %d, %v, want 1, true", count, ok)
+	}
+}
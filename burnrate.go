@@ -0,0 +1,158 @@
+// burnrate.go - Five-hour burn rate and a projection of when it hits the cap
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+const (
+	// Only samples this recent count, so the rate follows the current pace
+	burnRateWindow = 30 * time.Minute
+
+	// Fewer samples, or a shorter span, than this gives no estimate
+	minBurnSamples = 3
+	minBurnSpan    = 5 * time.Minute
+)
+
+// usageSample is one five-hour utilization reading
+type usageSample struct {
+	at          time.Time
+	utilization float64
+}
+
+// burnTracker keeps recent five-hour samples from the current window
+type burnTracker struct {
+	mu       sync.Mutex
+	samples  []usageSample
+	resetsAt time.Time // Window the samples belong to
+
+	// Window a predictive notification was sent for, so it fires once
+	warnedFor time.Time
+}
+
+// Fed by trackBurnRate in the running monitor
+var burn = &burnTracker{}
+
+// add records limit at time at. A new window (the reset time moved, or
+// utilization fell) discards the old samples.
+func (b *burnTracker) add(limit *claude.UsageLimit, at time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if limit == nil || limit.ResetsAtTime.IsZero() {
+		b.samples = nil
+		return
+	}
+	if n := len(b.samples); !limit.ResetsAtTime.Equal(b.resetsAt) || (n > 0 && limit.Utilization < b.samples[n-1].utilization) {
+		b.samples = nil
+		b.resetsAt = limit.ResetsAtTime
+	}
+
+	b.samples = append(b.samples, usageSample{at, limit.Utilization})
+	cutoff := at.Add(-burnRateWindow)
+	for len(b.samples) > 0 && b.samples[0].at.Before(cutoff) {
+		b.samples = b.samples[1:]
+	}
+}
+
+// rate returns the burn rate in percentage points per hour, or false if
+// there are too few samples to tell
+func (b *burnTracker) rate() (float64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.samples) < minBurnSamples {
+		return 0, false
+	}
+	first, last := b.samples[0], b.samples[len(b.samples)-1]
+	span := last.at.Sub(first.at)
+	if span < minBurnSpan {
+		return 0, false
+	}
+	return (last.utilization - first.utilization) / span.Hours(), true
+}
+
+// trackBurnRate feeds each fetch to the burn tracker
+func trackBurnRate(event UsageUpdated) {
+	burn.add(event.Limits.FiveHour, event.Limits.LastUpdated)
+}
+
+// Helper function to project how long until limit reaches 100% at
+// ratePerHour. Returns false if it won't get there before it resets.
+func timeToCap(limit *claude.UsageLimit, ratePerHour float64, now time.Time) (time.Duration, bool) {
+	if ratePerHour <= 0 || limit.Utilization >= 100 {
+		return 0, false
+	}
+	d := time.Duration((100 - limit.Utilization) / ratePerHour * float64(time.Hour))
+	if !limit.ResetsAtTime.IsZero() && now.Add(d).After(limit.ResetsAtTime) {
+		return 0, false
+	}
+	return d, true
+}
+
+// formatBurnRate describes the five-hour burn rate, e.g.
+// "Burn rate: 15%/h, at the limit in ~2h". Returns "" when there is no
+// estimate or usage isn't growing.
+func formatBurnRate(b *burnTracker, limit *claude.UsageLimit, now time.Time) string {
+	ratePerHour, ok := b.rate()
+	if !ok || limit == nil || ratePerHour <= 0 {
+		return ""
+	}
+
+	line := fmt.Sprintf("Burn rate: %s%%/h", formatPercentNumber(ratePerHour, false))
+	if d, ok := timeToCap(limit, ratePerHour, now); ok {
+		minutes := int(d.Minutes())
+		line += fmt.Sprintf(", at the limit in ~%s", formatDuration(minutes/60, minutes%60, " "))
+	} else {
+		line += ", resets before the limit"
+	}
+	return line
+}
+
+// checkBurnNotification warns once per window when the projected time to
+// the cap drops below notifyBeforeCapMinutes
+func checkBurnNotification(limit *claude.UsageLimit, now time.Time) {
+	config := currentConfig()
+	if config.NotifyBeforeCapMinutes <= 0 || limit == nil {
+		return
+	}
+	ratePerHour, ok := burn.rate()
+	if !ok {
+		return
+	}
+	d, ok := timeToCap(limit, ratePerHour, now)
+	if !ok || d > time.Duration(config.NotifyBeforeCapMinutes)*time.Minute {
+		return
+	}
+
+	burn.mu.Lock()
+	alreadyWarned := burn.warnedFor.Equal(limit.ResetsAtTime)
+	burn.warnedFor = limit.ResetsAtTime
+	burn.mu.Unlock()
+	if alreadyWarned {
+		return
+	}
+
+	minutes := int(d.Minutes())
+	message := fmt.Sprintf("At the current rate, the 5-Hour Session limit runs out in ~%s", formatDuration(minutes/60, minutes%60, " "))
+	sendNotificationAsync(message)
+}
+
+// recentBurnTracker builds a tracker from the history file and the current
+// reading, for commands that run without the monitor's in-memory samples
+func recentBurnTracker(limits *claude.UsageLimits) *burnTracker {
+	b := &burnTracker{}
+	now := time.Now()
+	if entries, err := readHistory(now.Add(-burnRateWindow)); err == nil {
+		for _, entry := range entries {
+			b.add(entry.Limits.FiveHour, entry.Timestamp)
+		}
+	}
+	b.add(limits.FiveHour, now)
+	return b
+}
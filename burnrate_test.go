@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+func TestBurnTrackerRate(t *testing.T) {
+	start := time.Date(2025, 6, 12, 14, 0, 0, 0, time.UTC)
+	reset := start.Add(3 * time.Hour)
+	sample := func(utilization float64) *claude.UsageLimit {
+		return &claude.UsageLimit{Utilization: utilization, ResetsAtTime: reset}
+	}
+
+	b := &burnTracker{}
+	b.add(sample(40), start)
+	b.add(sample(42), start.Add(5*time.Minute))
+	if _, ok := b.rate(); ok {
+		t.Error("rate() with two samples: want no estimate")
+	}
+
+	b.add(sample(45), start.Add(10*time.Minute))
+	if got, ok := b.rate(); !ok || got != 30 {
+		t.Errorf("rate() = %v, %v, want 30%%/h", got, ok)
+	}
+
+	// A new window starts the estimate over
+	b.add(&claude.UsageLimit{Utilization: 2, ResetsAtTime: reset.Add(5 * time.Hour)}, start.Add(15*time.Minute))
+	if _, ok := b.rate(); ok {
+		t.Error("rate() after a reset: want no estimate")
+	}
+
+	// Old samples age out of the window
+	b = &burnTracker{}
+	b.add(sample(10), start)
+	b.add(sample(40), start.Add(40*time.Minute))
+	b.add(sample(41), start.Add(45*time.Minute))
+	b.add(sample(42), start.Add(50*time.Minute))
+	if got, ok := b.rate(); !ok || got != 12 {
+		t.Errorf("rate() = %v, %v, want 12%%/h from recent samples only", got, ok)
+	}
+}
+
+func TestTimeToCap(t *testing.T) {
+	now := time.Date(2025, 6, 12, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		limit *claude.UsageLimit
+		rate  float64
+		want  time.Duration
+		ok    bool
+	}{
+		{"reaches cap", &claude.UsageLimit{Utilization: 60, ResetsAtTime: now.Add(4 * time.Hour)}, 20, 2 * time.Hour, true},
+		{"resets first", &claude.UsageLimit{Utilization: 60, ResetsAtTime: now.Add(time.Hour)}, 20, 0, false},
+		{"not growing", &claude.UsageLimit{Utilization: 60, ResetsAtTime: now.Add(4 * time.Hour)}, 0, 0, false},
+		{"already capped", &claude.UsageLimit{Utilization: 100}, 20, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := timeToCap(tt.limit, tt.rate, now)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("timeToCap() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
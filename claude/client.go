@@ -0,0 +1,789 @@
+// Package claude is a client for the claude.ai usage API, the same one the
+// menu bar monitor uses.
+package claude
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	claudeAPIBaseURL    = "https://claude.ai/api"
+	defaultUserAgent    = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36"
+	requestTimeout      = 10 * time.Second
+	maxIdleConns        = 2
+	maxIdleConnsPerHost = 1
+	idleConnTimeout     = 90 * time.Second
+
+	// Response bodies longer than this are truncated in debug traces
+	maxDebugBodyBytes = 2048
+)
+
+var (
+	// Typed errors for better error handling
+	ErrAuthFailed     = errors.New("authentication failed - session may have expired")
+	ErrOrgIDNotFound  = errors.New("organization ID not found in response")
+	ErrSessionExpired = errors.New("session expired")
+	ErrBlocked        = errors.New("blocked by a Cloudflare challenge or HTML error page - try again later or re-login in the browser")
+)
+
+// AuthMode selects how the credential is sent with each request
+type AuthMode string
+
+const (
+	AuthCookie AuthMode = "cookie" // sessionKey cookie, as the browser sends it
+	AuthBearer AuthMode = "bearer" // Authorization: Bearer header
+)
+
+type ClaudeUsageClient struct {
+	sessionKey string // the bearer token in AuthBearer mode
+	authMode   AuthMode
+	httpClient *http.Client
+	// Kept so each setter can rebuild httpClient without losing the others
+	httpOptions HTTPOptions
+	baseURL     string
+	userAgent   string
+	debugLog    func(format string, args ...any)
+
+	// Resolved lazily, possibly by concurrent requests, so guarded by orgMu
+	orgMu          sync.Mutex
+	organizationID string
+}
+
+// UsageLimits represents the real-time usage data from Claude
+// Based on actual API response from claude.ai/api/organizations/{org_id}/usage
+type UsageLimits struct {
+	FiveHour          *UsageLimit `json:"five_hour,omitempty"`
+	SevenDay          *UsageLimit `json:"seven_day,omitempty"`
+	SevenDayOAuthApps *UsageLimit `json:"seven_day_oauth_apps,omitempty"`
+	SevenDayOpus      *UsageLimit `json:"seven_day_opus,omitempty"`
+	IguanaNecktie     *UsageLimit `json:"iguana_necktie,omitempty"`
+	LastUpdated       time.Time   `json:"-"`
+}
+
+// All returns every limit field, including nil ones
+func (l *UsageLimits) All() []*UsageLimit {
+	return []*UsageLimit{l.FiveHour, l.SevenDay, l.SevenDayOAuthApps, l.SevenDayOpus, l.IguanaNecktie}
+}
+
+type UsageLimit struct {
+	Utilization  float64   `json:"utilization"`
+	ResetsAt     string    `json:"resets_at"`
+	ResetsAtTime time.Time `json:"-"`
+}
+
+// UnmarshalJSON accepts resets_at as a string or a bare number and parses
+// it into ResetsAtTime
+func (l *UsageLimit) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Utilization float64         `json:"utilization"`
+		ResetsAt    json.RawMessage `json:"resets_at"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	l.Utilization = raw.Utilization
+	l.ResetsAt = ""
+	if len(raw.ResetsAt) > 0 && string(raw.ResetsAt) != "null" {
+		if err := json.Unmarshal(raw.ResetsAt, &l.ResetsAt); err != nil {
+			// Not a string, so keep the number's text
+			l.ResetsAt = string(raw.ResetsAt)
+		}
+	}
+	l.ResetsAtTime = parseResetTime(l.ResetsAt)
+	return nil
+}
+
+// Epoch values above this are taken as milliseconds (it's 2001 in ms, and
+// far beyond any plausible reset in seconds)
+const unixMillisThreshold = 1e12
+
+// parseResetTime parses an RFC3339 timestamp (with or without fractional
+// seconds) or Unix seconds/milliseconds. Anything else gives the zero time.
+func parseResetTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+
+	if n, err := strconv.ParseFloat(s, 64); err == nil && n > 0 {
+		if n >= unixMillisThreshold {
+			return time.UnixMilli(int64(n))
+		}
+		return time.Unix(int64(n), 0)
+	}
+	return time.Time{}
+}
+
+// HTTPOptions are the connection settings for NewHTTPClient. The zero value
+// is the default: pooled connections, system CAs and dual-stack dialing.
+type HTTPOptions struct {
+	DisableKeepAlives bool
+	TLSConfig         *tls.Config // nil verifies against the system CAs
+	IPVersion         IPVersion
+}
+
+// IPVersion restricts which IP version connections use
+type IPVersion string
+
+const (
+	IPAny IPVersion = ""  // Dual-stack, the default
+	IPv4  IPVersion = "4" // For networks with a broken IPv6 route
+	IPv6  IPVersion = "6"
+)
+
+// Match http.DefaultTransport's dialer
+const (
+	dialTimeout   = 30 * time.Second
+	dialKeepAlive = 30 * time.Second
+)
+
+// NewHTTPClient creates an HTTP client with the usage client's timeout and
+// proxy settings (HTTPS_PROXY etc.) and its own connection pool, so
+// clients for different sessions never share connections
+func NewHTTPClient(options HTTPOptions) *http.Client {
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     options.TLSConfig,
+		MaxIdleConns:        maxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		IdleConnTimeout:     idleConnTimeout,
+		DisableCompression:  false,
+		DisableKeepAlives:   options.DisableKeepAlives,
+	}
+	if options.IPVersion != IPAny {
+		dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: dialKeepAlive}
+		network := "tcp" + string(options.IPVersion)
+		transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+
+	return &http.Client{
+		Timeout:       requestTimeout,
+		CheckRedirect: checkRedirect,
+		Transport:     transport,
+	}
+}
+
+// Path segments of the pages an expired session gets redirected to
+var loginPathSegments = []string{"login", "logout", "signin", "sign-in", "auth", "oauth"}
+
+// Same limit as the http package's default policy
+const maxRedirects = 10
+
+// checkRedirect stops at a redirect to a login page, which claude.ai sends
+// instead of a 401 for some expired sessions, so callers get ErrAuthFailed
+// rather than a parse error on the login page's HTML
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	for _, segment := range strings.Split(strings.ToLower(req.URL.Path), "/") {
+		if slices.Contains(loginPathSegments, segment) {
+			return fmt.Errorf("%w (redirected to %s)", ErrAuthFailed, req.URL.Path)
+		}
+	}
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	return nil
+}
+
+// LoadTLSConfig builds a TLS config that also trusts the PEM certificates in
+// caFile, for proxies that intercept TLS with their own CA, and presents the
+// client certificate in certFile/keyFile. Verification is never skipped.
+// Returns nil when no file is given.
+func LoadTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA file: %w", err)
+		}
+		// Added to the system CAs so hosts the proxy doesn't intercept
+		// still verify
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed to load CA file %s: no PEM certificates found", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("a client certificate needs both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func NewClaudeUsageClient(sessionKey string) *ClaudeUsageClient {
+	return &ClaudeUsageClient{
+		sessionKey: sessionKey,
+		authMode:   AuthCookie,
+		httpClient: NewHTTPClient(HTTPOptions{}),
+		baseURL:    claudeAPIBaseURL,
+		userAgent:  defaultUserAgent,
+	}
+}
+
+func NewClaudeUsageClientWithOrg(sessionKey, organizationID string) *ClaudeUsageClient {
+	return &ClaudeUsageClient{
+		sessionKey:     sessionKey,
+		organizationID: organizationID,
+		authMode:       AuthCookie,
+		httpClient:     NewHTTPClient(HTTPOptions{}),
+		baseURL:        claudeAPIBaseURL,
+		userAgent:      defaultUserAgent,
+	}
+}
+
+// SetDisableKeepAlives makes every request open a fresh connection instead of
+// reusing pooled ones, for networks where idle connections go stale
+func (c *ClaudeUsageClient) SetDisableKeepAlives(disable bool) {
+	c.httpOptions.DisableKeepAlives = disable
+	c.httpClient = NewHTTPClient(c.httpOptions)
+}
+
+// SetTLSConfig replaces the TLS settings, e.g. from LoadTLSConfig; nil
+// restores the system defaults
+func (c *ClaudeUsageClient) SetTLSConfig(config *tls.Config) {
+	c.httpOptions.TLSConfig = config
+	c.httpClient = NewHTTPClient(c.httpOptions)
+}
+
+// SetIPVersion restricts connections to IPv4 or IPv6; empty allows both
+func (c *ClaudeUsageClient) SetIPVersion(version IPVersion) error {
+	switch version {
+	case IPAny, IPv4, IPv6:
+	default:
+		return fmt.Errorf("unknown IP version %q (want %q or %q)", version, IPv4, IPv6)
+	}
+	c.httpOptions.IPVersion = version
+	c.httpClient = NewHTTPClient(c.httpOptions)
+	return nil
+}
+
+// CloseIdleConnections drops pooled connections, which are likely dead after
+// the machine wakes from sleep, so the next request dials afresh
+func (c *ClaudeUsageClient) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
+
+// SetTimeout changes the per-request timeout, e.g. for a quick check.
+// It can only shorten the current timeout; longer or non-positive values are
+// ignored.
+func (c *ClaudeUsageClient) SetTimeout(timeout time.Duration) {
+	if timeout > 0 && timeout < c.httpClient.Timeout {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// SetBaseURL points the client at another API root, e.g. a test server or a
+// debugging proxy. Empty restores the default.
+func (c *ClaudeUsageClient) SetBaseURL(baseURL string) {
+	if baseURL == "" {
+		baseURL = claudeAPIBaseURL
+	}
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+// SetUserAgent overrides the User-Agent header; empty restores the default
+func (c *ClaudeUsageClient) SetUserAgent(userAgent string) {
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	c.userAgent = userAgent
+}
+
+// SetAuthMode chooses how the credential is sent; empty means AuthCookie
+func (c *ClaudeUsageClient) SetAuthMode(mode AuthMode) error {
+	switch mode {
+	case "":
+		c.authMode = AuthCookie
+	case AuthCookie, AuthBearer:
+		c.authMode = mode
+	default:
+		return fmt.Errorf("unknown auth mode %q (want %q or %q)", mode, AuthCookie, AuthBearer)
+	}
+	return nil
+}
+
+// SetDebugLog sets a function that receives a trace of every request's URL,
+// headers, status code and (truncated) response body, with the session key
+// masked. nil disables tracing.
+func (c *ClaudeUsageClient) SetDebugLog(logf func(format string, args ...any)) {
+	c.debugLog = logf
+}
+
+// traceExchange logs a finished request if debug tracing is enabled
+func (c *ClaudeUsageClient) traceExchange(req *http.Request, resp *http.Response, body []byte) {
+	if c.debugLog != nil {
+		c.debugLog("%s", formatExchange(req, resp, body))
+	}
+}
+
+// formatExchange renders a request and its response for the debug log
+func formatExchange(req *http.Request, resp *http.Response, body []byte) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "HTTP %s %s -> %d\n", req.Method, req.URL, resp.StatusCode)
+
+	headers := req.Header.Clone()
+	if cookie, err := req.Cookie("sessionKey"); err == nil {
+		headers.Set("Cookie", "sessionKey="+RedactSessionKey(cookie.Value))
+	}
+	if token, ok := strings.CutPrefix(headers.Get("Authorization"), "Bearer "); ok {
+		headers.Set("Authorization", "Bearer "+RedactSessionKey(token))
+	}
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		// Catches a key in any other header too
+		fmt.Fprintf(&b, "  > %s: %s\n", name, RedactSecrets(strings.Join(headers[name], ", ")))
+	}
+	fmt.Fprintf(&b, "  < Content-Type: %s\n", resp.Header.Get("Content-Type"))
+
+	if len(body) > maxDebugBodyBytes {
+		fmt.Fprintf(&b, "  %s... (%d bytes truncated)", body[:maxDebugBodyBytes], len(body)-maxDebugBodyBytes)
+	} else {
+		fmt.Fprintf(&b, "  %s", body)
+	}
+	return b.String()
+}
+
+// OrganizationID returns the organization ID, once known
+func (c *ClaudeUsageClient) OrganizationID() string {
+	c.orgMu.Lock()
+	defer c.orgMu.Unlock()
+	return c.organizationID
+}
+
+func (c *ClaudeUsageClient) setOrganizationID(id string) {
+	c.orgMu.Lock()
+	defer c.orgMu.Unlock()
+	c.organizationID = id
+}
+
+// setRequestHeaders applies the authentication and browser headers every request needs
+func (c *ClaudeUsageClient) setRequestHeaders(req *http.Request) {
+	c.setAuthHeader(req)
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "application/json")
+}
+
+// setAuthHeader attaches the credential in the configured auth mode
+func (c *ClaudeUsageClient) setAuthHeader(req *http.Request) {
+	switch c.authMode {
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+c.sessionKey)
+	default:
+		req.Header.Set("Cookie", fmt.Sprintf("sessionKey=%s", c.sessionKey))
+	}
+}
+
+// GetUsageLimits fetches real-time usage limits from Claude API
+func (c *ClaudeUsageClient) GetUsageLimits() (*UsageLimits, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	return c.GetUsageLimitsContext(ctx)
+}
+
+// GetUsageLimitsContext is GetUsageLimits with a caller-supplied context,
+// which bounds both the organization lookup and the usage request. The
+// client's own timeout still applies to each request.
+func (c *ClaudeUsageClient) GetUsageLimitsContext(ctx context.Context) (*UsageLimits, error) {
+	// First, get organization ID if not already cached
+	if c.OrganizationID() == "" {
+		if err := c.fetchOrganizationID(ctx); err != nil {
+			return nil, fmt.Errorf("failed to get organization ID: %w", err)
+		}
+	}
+
+	// Build the actual endpoint
+	url := fmt.Sprintf("%s/organizations/%s/usage", c.baseURL, c.OrganizationID())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, newClientError(CategoryConfig, 0, fmt.Errorf("failed to create request: %w", err))
+	}
+
+	c.setRequestHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, requestError(fmt.Errorf("failed to fetch usage limits: %w", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, newClientError(CategoryNetwork, resp.StatusCode, fmt.Errorf("failed to read response: %w", err))
+	}
+	c.traceExchange(req, resp, body)
+
+	// Cloudflare challenges come back as HTML, often with a 403 or even a 200
+	if isHTMLResponse(resp, body) && (resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusForbidden) {
+		return nil, newClientError(CategoryBlocked, resp.StatusCode, fmt.Errorf("%w (status %d)", ErrBlocked, resp.StatusCode))
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, newClientError(CategoryAuth, resp.StatusCode, fmt.Errorf("%w (status %d)", ErrAuthFailed, resp.StatusCode))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newClientError(statusCategory(resp.StatusCode), resp.StatusCode,
+			fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body)))
+	}
+
+	var limits UsageLimits
+	if err := json.Unmarshal(body, &limits); err != nil {
+		return nil, newClientError(CategoryParse, resp.StatusCode, fmt.Errorf("failed to parse response: %w", err))
+	}
+
+	// Reset times are parsed by UsageLimit.UnmarshalJSON
+	limits.LastUpdated = time.Now()
+	return &limits, nil
+}
+
+// GetRawUsage fetches the usage endpoint's body without parsing it, for
+// inspecting the response when the API changes shape
+func (c *ClaudeUsageClient) GetRawUsage() ([]byte, error) {
+	if c.OrganizationID() == "" {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		if err := c.fetchOrganizationID(ctx); err != nil {
+			return nil, fmt.Errorf("failed to get organization ID: %w", err)
+		}
+	}
+	return c.getJSON("/organizations/" + c.OrganizationID() + "/usage")
+}
+
+// fetchOrganizationID retrieves the organization ID from the account endpoint
+func (c *ClaudeUsageClient) fetchOrganizationID(ctx context.Context) error {
+	// Try to get organization ID from account/organizations endpoint
+	url := fmt.Sprintf("%s/organizations", c.baseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return newClientError(CategoryConfig, 0, fmt.Errorf("failed to create request: %w", err))
+	}
+
+	c.setRequestHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return requestError(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return newClientError(CategoryNetwork, resp.StatusCode, err)
+	}
+	c.traceExchange(req, resp, body)
+
+	if isHTMLResponse(resp, body) && (resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusForbidden) {
+		return newClientError(CategoryBlocked, resp.StatusCode, fmt.Errorf("%w (status %d)", ErrBlocked, resp.StatusCode))
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		// A mistyped key fails here first, before any usage request
+		return newClientError(CategoryAuth, resp.StatusCode, fmt.Errorf("%w (status %d)", ErrAuthFailed, resp.StatusCode))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return newClientError(statusCategory(resp.StatusCode), resp.StatusCode,
+			fmt.Errorf("failed to fetch organizations (status %d)", resp.StatusCode))
+	}
+
+	id, err := extractOrganizationID(body)
+	if err != nil {
+		category := CategoryNotFound
+		if !json.Valid(body) {
+			category = CategoryParse
+		}
+		return newClientError(category, resp.StatusCode, err)
+	}
+	c.setOrganizationID(id)
+	return nil
+}
+
+// Keys that may hold organizations, checked before an object's own ID so an
+// account or membership wrapper isn't mistaken for the organization
+var organizationContainerKeys = []string{"organizations", "organization", "memberships", "account", "data"}
+
+// Nested shapes seen so far are at most a few levels deep
+const maxOrgSearchDepth = 4
+
+// extractOrganizationID finds the first organization ID in an organizations
+// response: a bare array or object, or one nested under memberships, an
+// account, or a top-level wrapper key
+func extractOrganizationID(body []byte) (string, error) {
+	var parsed any
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrOrgIDNotFound, err)
+	}
+	if id, ok := findOrganizationID(parsed, 0); ok {
+		return id, nil
+	}
+	return "", ErrOrgIDNotFound
+}
+
+// findOrganizationID searches v depth-first for an organization ID
+func findOrganizationID(v any, depth int) (string, bool) {
+	if depth > maxOrgSearchDepth {
+		return "", false
+	}
+
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			if id, ok := findOrganizationID(item, depth+1); ok {
+				return id, true
+			}
+		}
+	case map[string]any:
+		for _, key := range organizationContainerKeys {
+			if nested, ok := v[key]; ok {
+				if id, ok := findOrganizationID(nested, depth+1); ok {
+					return id, true
+				}
+			}
+		}
+		for _, key := range []string{"uuid", "id"} {
+			if id, ok := v[key].(string); ok && id != "" {
+				return id, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Organization is the organization whose usage is monitored
+type Organization struct {
+	ID   string
+	Name string // Empty if the organizations endpoint doesn't list one
+	Plan string // Subscription plan, e.g. "Max 20x"; empty if not listed
+}
+
+// GetOrganization returns the monitored organization, resolving the ID first
+// if the client wasn't given one
+func (c *ClaudeUsageClient) GetOrganization() (*Organization, error) {
+	body, err := c.getJSON("/organizations")
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed any
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, newClientError(CategoryParse, http.StatusOK, fmt.Errorf("failed to parse organizations: %w", err))
+	}
+
+	id := c.OrganizationID()
+	if id == "" {
+		if id, err = extractOrganizationID(body); err != nil {
+			return nil, newClientError(CategoryNotFound, http.StatusOK, err)
+		}
+		c.setOrganizationID(id)
+	}
+
+	org := &Organization{ID: id}
+	if found, ok := findOrganization(parsed, id, 0); ok {
+		org.Name, _ = found["name"].(string)
+		org.Plan = planName(found)
+	}
+	return org, nil
+}
+
+// GetPlan returns the monitored organization's subscription plan, e.g. "Pro"
+// or "Max 20x", or "" if claude.ai doesn't say
+func (c *ClaudeUsageClient) GetPlan() (string, error) {
+	org, err := c.GetOrganization()
+	if err != nil {
+		return "", err
+	}
+	return org.Plan, nil
+}
+
+// findOrganization searches v for the object with the given ID
+func findOrganization(v any, id string, depth int) (map[string]any, bool) {
+	if depth > maxOrgSearchDepth {
+		return nil, false
+	}
+
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			if org, ok := findOrganization(item, id, depth+1); ok {
+				return org, true
+			}
+		}
+	case map[string]any:
+		if v["uuid"] == id || v["id"] == id {
+			return v, true
+		}
+		for _, key := range organizationContainerKeys {
+			if org, ok := findOrganization(v[key], id, depth+1); ok {
+				return org, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// planName reads an organization's plan from its rate limit tier, e.g.
+// "default_claude_max_20x" becomes "Max 20x", falling back to the plan
+// named in its capabilities. Unlisted plans give "".
+func planName(org map[string]any) string {
+	if tier, ok := org["rate_limit_tier"].(string); ok && tier != "" {
+		tier = strings.TrimPrefix(tier, "default_")
+		tier = strings.TrimPrefix(tier, "claude_")
+		if tier == "ai" {
+			// The free tier is plain "default_claude_ai"
+			return "Free"
+		}
+
+		words := strings.Split(tier, "_")
+		for i, word := range words {
+			// Multipliers like "20x" stay lowercase
+			if word != "" && (word[0] < '0' || word[0] > '9') {
+				words[i] = strings.ToUpper(word[:1]) + word[1:]
+			}
+		}
+		return strings.Join(words, " ")
+	}
+
+	capabilities, _ := org["capabilities"].([]any)
+	for _, capability := range capabilities {
+		switch capability {
+		case "claude_max":
+			return "Max"
+		case "claude_pro":
+			return "Pro"
+		}
+	}
+	return ""
+}
+
+// AccountProfile identifies the logged-in account. Fields the endpoint
+// doesn't provide are left empty.
+type AccountProfile struct {
+	Name  string
+	Email string
+}
+
+// GetAccountProfile fetches the account's name and email
+func (c *ClaudeUsageClient) GetAccountProfile() (*AccountProfile, error) {
+	body, err := c.getJSON("/account")
+	if err != nil {
+		return nil, err
+	}
+
+	var account map[string]any
+	if err := json.Unmarshal(body, &account); err != nil {
+		return nil, newClientError(CategoryParse, http.StatusOK, fmt.Errorf("failed to parse response: %w", err))
+	}
+
+	// Key names vary; take the first one present
+	firstString := func(keys ...string) string {
+		for _, key := range keys {
+			if value, ok := account[key].(string); ok && value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	return &AccountProfile{
+		Name:  firstString("full_name", "display_name", "name"),
+		Email: firstString("email_address", "email"),
+	}, nil
+}
+
+// getJSON performs an authenticated GET of path (relative to the API base URL)
+// and returns the body, mapping auth failures and HTML pages to typed errors
+func (c *ClaudeUsageClient) getJSON(path string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+	if err != nil {
+		return nil, newClientError(CategoryConfig, 0, fmt.Errorf("failed to create request: %w", err))
+	}
+	c.setRequestHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, requestError(fmt.Errorf("request failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, newClientError(CategoryNetwork, resp.StatusCode, fmt.Errorf("failed to read response: %w", err))
+	}
+	c.traceExchange(req, resp, body)
+
+	if isHTMLResponse(resp, body) && (resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusForbidden) {
+		return nil, newClientError(CategoryBlocked, resp.StatusCode, fmt.Errorf("%w (status %d)", ErrBlocked, resp.StatusCode))
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, newClientError(CategoryAuth, resp.StatusCode, fmt.Errorf("%w (status %d)", ErrAuthFailed, resp.StatusCode))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newClientError(statusCategory(resp.StatusCode), resp.StatusCode,
+			fmt.Errorf("unexpected status code %d", resp.StatusCode))
+	}
+	return body, nil
+}
+
+// isHTMLResponse reports whether a response is an HTML page rather than JSON
+func isHTMLResponse(resp *http.Response, body []byte) bool {
+	if strings.Contains(resp.Header.Get("Content-Type"), "text/html") {
+		return true
+	}
+	start := strings.ToLower(strings.TrimSpace(string(body[:min(len(body), 64)])))
+	return strings.HasPrefix(start, "<!doctype") || strings.HasPrefix(start, "<html")
+}
+
+// TestSession tests if the session key is still valid
+func (c *ClaudeUsageClient) TestSession() error {
+	_, err := c.GetUsageLimits()
+	if errors.Is(err, ErrAuthFailed) {
+		statusCode := 0
+		var clientErr *ClientError
+		if errors.As(err, &clientErr) {
+			statusCode = clientErr.StatusCode
+		}
+		return newClientError(CategoryAuth, statusCode, ErrSessionExpired)
+	}
+	return err
+}
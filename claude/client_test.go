@@ -0,0 +1,585 @@
+package claude
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsHTMLResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        bool
+	}{
+		{"json", "application/json", `{"five_hour":null}`, false},
+		{"html content type", "text/html; charset=UTF-8", "Just a moment...", true},
+		{"doctype without content type", "", "<!DOCTYPE html><html>", true},
+		{"html tag with leading whitespace", "", "\n  <html lang=\"en\">", true},
+		{"empty body", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			resp.Header.Set("Content-Type", tt.contentType)
+			if got := isHTMLResponse(resp, []byte(tt.body)); got != tt.want {
+				t.Errorf("isHTMLResponse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatExchange(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://claude.ai/api/organizations", nil)
+	req.Header.Set("Cookie", "sessionKey=sk-ant-REDACTED")
+	req.Header.Set("Accept", "application/json")
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+
+	got := formatExchange(req, resp, []byte(strings.Repeat("x", maxDebugBodyBytes+10)))
+
+	if strings.Contains(got, "secretsecret") {
+		t.Errorf("formatExchange() leaked the session key:\n%s", got)
+	}
+	for _, want := range []string{"GET https://claude.ai/api/organizations -> 200", "Cookie: sessionKey=sk-ant-sid…AbCd", "(10 bytes truncated)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("formatExchange() missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestExtractOrganizationID(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "array of organizations",
+			body: `[{"uuid":"org-1","name":"Personal","capabilities":["chat"]},{"uuid":"org-2"}]`,
+			want: "org-1",
+		},
+		{
+			name: "single organization with id",
+			body: `{"id":"org-1","name":"Personal"}`,
+			want: "org-1",
+		},
+		{
+			name: "wrapped in organizations key",
+			body: `{"organizations":[{"uuid":"org-1","name":"Personal"}]}`,
+			want: "org-1",
+		},
+		{
+			name: "wrapped in data key",
+			body: `{"data":{"uuid":"org-1"}}`,
+			want: "org-1",
+		},
+		{
+			name: "memberships",
+			body: `{"memberships":[{"role":"admin","organization":{"uuid":"org-1","name":"Team"}}]}`,
+			want: "org-1",
+		},
+		{
+			name: "account with memberships",
+			body: `{"account":{"uuid":"acct-1","email_address":"a@example.com","memberships":[{"organization":{"uuid":"org-1"}}]}}`,
+			want: "org-1",
+		},
+		{
+			name: "array skips entries without an id",
+			body: `[{"name":"broken"},{"uuid":"org-2"}]`,
+			want: "org-2",
+		},
+		{
+			name:    "empty array",
+			body:    `[]`,
+			wantErr: true,
+		},
+		{
+			name:    "no id anywhere",
+			body:    `{"account":{"memberships":[]}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			body:    `not json`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractOrganizationID([]byte(tt.body))
+			if tt.wantErr {
+				if !errors.Is(err, ErrOrgIDNotFound) {
+					t.Errorf("extractOrganizationID() error = %v, want ErrOrgIDNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractOrganizationID() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("extractOrganizationID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetAuthHeader(t *testing.T) {
+	tests := []struct {
+		mode       AuthMode
+		wantCookie string
+		wantAuth   string
+	}{
+		{"", "sessionKey=sk-test", ""},
+		{AuthCookie, "sessionKey=sk-test", ""},
+		{AuthBearer, "", "Bearer sk-test"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			client := NewClaudeUsageClient("sk-test")
+			if err := client.SetAuthMode(tt.mode); err != nil {
+				t.Fatalf("SetAuthMode(%q) error = %v", tt.mode, err)
+			}
+
+			req, _ := http.NewRequest("GET", claudeAPIBaseURL, nil)
+			client.setRequestHeaders(req)
+			if got := req.Header.Get("Cookie"); got != tt.wantCookie {
+				t.Errorf("Cookie = %q, want %q", got, tt.wantCookie)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", got, tt.wantAuth)
+			}
+		})
+	}
+
+	if err := NewClaudeUsageClient("sk-test").SetAuthMode("oauth"); err == nil {
+		t.Error("SetAuthMode(\"oauth\") succeeded, want an error")
+	}
+}
+
+func TestUsageLimitResetTime(t *testing.T) {
+	want := time.Date(2025, 6, 12, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		resetsAt string
+		want     time.Time
+	}{
+		{"rfc3339", `"2025-06-12T14:30:00Z"`, want},
+		{"rfc3339 with offset", `"2025-06-12T16:30:00+02:00"`, want},
+		{"rfc3339 nano", `"2025-06-12T14:30:00.123456+00:00"`, want.Add(123456 * time.Microsecond)},
+		{"unix seconds", `1749738600`, want},
+		{"unix millis", `1749738600000`, want},
+		{"unix seconds as string", `"1749738600"`, want},
+		{"null", `null`, time.Time{}},
+		{"invalid", `"next tuesday"`, time.Time{}},
+		{"negative", `-5`, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var limit UsageLimit
+			body := `{"utilization": 42, "resets_at": ` + tt.resetsAt + `}`
+			if err := json.Unmarshal([]byte(body), &limit); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !limit.ResetsAtTime.Equal(tt.want) {
+				t.Errorf("ResetsAtTime = %v, want %v", limit.ResetsAtTime, tt.want)
+			}
+			if limit.Utilization != 42 {
+				t.Errorf("Utilization = %v, want 42", limit.Utilization)
+			}
+		})
+	}
+}
+
+func TestFindOrganization(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		id   string
+		want string
+	}{
+		{"array", `[{"uuid":"org-1","name":"Personal"},{"uuid":"org-2","name":"Team"}]`, "org-2", "Team"},
+		{"memberships", `{"memberships":[{"organization":{"uuid":"org-1","name":"Team"}}]}`, "org-1", "Team"},
+		{"no name", `[{"uuid":"org-1"}]`, "org-1", ""},
+		{"other org", `[{"uuid":"org-1","name":"Personal"}]`, "org-9", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parsed any
+			if err := json.Unmarshal([]byte(tt.body), &parsed); err != nil {
+				t.Fatal(err)
+			}
+			org, _ := findOrganization(parsed, tt.id, 0)
+			if got, _ := org["name"].(string); got != tt.want {
+				t.Errorf("findOrganization() name = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanName(t *testing.T) {
+	tests := []struct {
+		name string
+		org  string
+		want string
+	}{
+		{"max 20x", `{"rate_limit_tier":"default_claude_max_20x"}`, "Max 20x"},
+		{"max 5x", `{"rate_limit_tier":"default_claude_max_5x","capabilities":["chat","claude_max"]}`, "Max 5x"},
+		{"pro", `{"rate_limit_tier":"default_claude_pro"}`, "Pro"},
+		{"free", `{"rate_limit_tier":"default_claude_ai"}`, "Free"},
+		{"unknown tier", `{"rate_limit_tier":"claude_team_premium"}`, "Team Premium"},
+		{"capabilities only", `{"capabilities":["chat","claude_pro"]}`, "Pro"},
+		{"not listed", `{"uuid":"org-1","capabilities":["chat"]}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var org map[string]any
+			if err := json.Unmarshal([]byte(tt.org), &org); err != nil {
+				t.Fatal(err)
+			}
+			if got := planName(org); got != tt.want {
+				t.Errorf("planName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetBaseURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cookie") != "sessionKey=sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/organizations":
+			w.Write([]byte(`[{"uuid":"org-1"}]`))
+		case "/api/organizations/org-1/usage":
+			w.Write([]byte(`{"five_hour":{"utilization":42,"resets_at":null}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClaudeUsageClient("sk-test")
+	client.SetBaseURL(server.URL + "/api/")
+
+	limits, err := client.GetUsageLimits()
+	if err != nil {
+		t.Fatalf("GetUsageLimits() error = %v", err)
+	}
+	if limits.FiveHour == nil || limits.FiveHour.Utilization != 42 {
+		t.Errorf("GetUsageLimits() five_hour = %+v, want 42%%", limits.FiveHour)
+	}
+	if got := client.OrganizationID(); got != "org-1" {
+		t.Errorf("OrganizationID() = %q, want org-1", got)
+	}
+
+	// A wrong key is rejected by the organizations lookup first
+	wrong := NewClaudeUsageClient("sk-wrong")
+	wrong.SetBaseURL(server.URL + "/api/")
+	if _, err := wrong.GetUsageLimits(); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("GetUsageLimits() with a wrong key error = %v, want ErrAuthFailed", err)
+	}
+
+	// The plan lookup and the first fetch may both resolve the organization
+	fresh := NewClaudeUsageClient("sk-test")
+	fresh.SetBaseURL(server.URL + "/api/")
+	planDone := make(chan error)
+	go func() {
+		_, err := fresh.GetPlan()
+		planDone <- err
+	}()
+	if _, err := fresh.GetUsageLimits(); err != nil {
+		t.Errorf("GetUsageLimits() alongside GetPlan() error = %v", err)
+	}
+	if err := <-planDone; err != nil {
+		t.Errorf("GetPlan() alongside GetUsageLimits() error = %v", err)
+	}
+
+	client.SetBaseURL("")
+	if client.baseURL != claudeAPIBaseURL {
+		t.Errorf("SetBaseURL(\"\") left baseURL = %q, want the default", client.baseURL)
+	}
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	// Stands in for a proxy re-signing traffic with a private CA
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"five_hour":{"utilization":42,"resets_at":null}}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	notPEM := filepath.Join(dir, "not.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClaudeUsageClientWithOrg("sk-test", "org-1")
+	client.SetBaseURL(server.URL)
+	if _, err := client.GetUsageLimits(); err == nil {
+		t.Fatal("GetUsageLimits() trusted the private CA without caFile")
+	}
+
+	config, err := LoadTLSConfig(caFile, "", "")
+	if err != nil {
+		t.Fatalf("LoadTLSConfig() error = %v", err)
+	}
+	client.SetTLSConfig(config)
+	// Rebuilding the HTTP client must keep the CA
+	client.SetDisableKeepAlives(true)
+	if _, err := client.GetUsageLimits(); err != nil {
+		t.Errorf("GetUsageLimits() with caFile error = %v", err)
+	}
+
+	if config, err := LoadTLSConfig("", "", ""); config != nil || err != nil {
+		t.Errorf("LoadTLSConfig() with no files = %v, %v, want nil, nil", config, err)
+	}
+	for _, tt := range []struct {
+		name                      string
+		caFile, certFile, keyFile string
+	}{
+		{"missing CA file", filepath.Join(dir, "missing.pem"), "", ""},
+		{"CA file without certificates", notPEM, "", ""},
+		{"certificate without key", "", caFile, ""},
+		{"invalid client certificate", "", notPEM, notPEM},
+	} {
+		if _, err := LoadTLSConfig(tt.caFile, tt.certFile, tt.keyFile); err == nil {
+			t.Errorf("LoadTLSConfig() with %s succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestRedactSessionKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"sk-ant-REDACTED", "sk-ant-sid…x7Qz"},
+		{"sk-ant-short", "[redacted]"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := RedactSessionKey(tt.key); got != tt.want {
+			t.Errorf("RedactSessionKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	line := `failed with sessionKey=sk-ant-REDACTED; retrying "sk-ant-REDACTED"`
+	want := `failed with sessionKey=sk-ant-sid…x7Qz; retrying "sk-ant-oat…Ab12"`
+	if got := RedactSecrets(line); got != want {
+		t.Errorf("RedactSecrets() = %q, want %q", got, want)
+	}
+	if got := RedactSecrets(want); got != want {
+		t.Errorf("RedactSecrets() on masked keys = %q, want them unchanged", got)
+	}
+	if got := RedactSecrets("no secrets here"); got != "no secrets here" {
+		t.Errorf("RedactSecrets() changed a line without keys: %q", got)
+	}
+}
+
+func TestClientErrorCategories(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		html       bool
+		want       ErrorCategory
+		wantStatus int
+		sentinel   error // Must still match with errors.Is
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{}`, false, CategoryAuth, 401, ErrAuthFailed},
+		{"cloudflare", http.StatusForbidden, "<!DOCTYPE html><title>Just a moment</title>", true, CategoryBlocked, 403, ErrBlocked},
+		{"rate limited", http.StatusTooManyRequests, `{}`, false, CategoryRateLimit, 429, nil},
+		{"wrong organization", http.StatusNotFound, `{}`, false, CategoryNotFound, 404, nil},
+		{"server error", http.StatusBadGateway, `{}`, false, CategoryServer, 502, nil},
+		{"not JSON", http.StatusOK, `{"five_hour":`, false, CategoryParse, 200, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.html {
+					w.Header().Set("Content-Type", "text/html")
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := NewClaudeUsageClientWithOrg("sk-test", "org-1")
+			client.SetBaseURL(server.URL)
+			_, err := client.GetUsageLimits()
+
+			var clientErr *ClientError
+			if !errors.As(err, &clientErr) {
+				t.Fatalf("GetUsageLimits() error = %v (%T), want a *ClientError", err, err)
+			}
+			if clientErr.Category != tt.want || clientErr.StatusCode != tt.wantStatus {
+				t.Errorf("ClientError = %s/%d, want %s/%d", clientErr.Category, clientErr.StatusCode, tt.want, tt.wantStatus)
+			}
+			if tt.sentinel != nil && !errors.Is(err, tt.sentinel) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.sentinel)
+			}
+		})
+	}
+
+	// The organization lookup fails the same way
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	client := NewClaudeUsageClient("sk-test")
+	client.SetBaseURL(server.URL)
+	_, err := client.GetUsageLimits()
+	if CategoryOf(err) != CategoryNotFound || !errors.Is(err, ErrOrgIDNotFound) {
+		t.Errorf("GetUsageLimits() with no organizations = %v (category %q), want not-found ErrOrgIDNotFound", err, CategoryOf(err))
+	}
+
+	// A changed response shape is an error, not an empty organization
+	garbled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"uuid":"org-1",`))
+	}))
+	defer garbled.Close()
+	withOrg := NewClaudeUsageClientWithOrg("sk-test", "org-1")
+	withOrg.SetBaseURL(garbled.URL)
+	if org, err := withOrg.GetOrganization(); CategoryOf(err) != CategoryParse {
+		t.Errorf("GetOrganization() of truncated JSON = %+v, %v, want a parse error", org, err)
+	}
+	if profile, err := withOrg.GetAccountProfile(); CategoryOf(err) != CategoryParse {
+		t.Errorf("GetAccountProfile() of truncated JSON = %+v, %v, want a parse error", profile, err)
+	}
+
+	server.Close()
+	_, err = client.GetUsageLimits()
+	if CategoryOf(err) != CategoryNetwork {
+		t.Errorf("GetUsageLimits() with the server down category = %q, want network", CategoryOf(err))
+	}
+	if err := client.TestSession(); errors.Is(err, ErrSessionExpired) {
+		t.Errorf("TestSession() with the server down = %v, want a network error", err)
+	}
+	if CategoryOf(errors.New("other")) != "" {
+		t.Error("CategoryOf() of a plain error is not empty")
+	}
+
+	// A request that can't be built is a setup problem, not a network one
+	for _, malformed := range []*ClaudeUsageClient{withOrg, NewClaudeUsageClient("sk-test")} {
+		malformed.SetBaseURL("http://bad host")
+		if _, err := malformed.GetUsageLimits(); CategoryOf(err) != CategoryConfig {
+			t.Errorf("GetUsageLimits() with a malformed base URL = %v (category %q), want %q", err, CategoryOf(err), CategoryConfig)
+		}
+	}
+}
+
+func TestLoginRedirectIsAuthFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/organizations":
+			// A harmless redirect is still followed
+			http.Redirect(w, r, "/api/v2/organizations", http.StatusFound)
+		case "/api/v2/organizations":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[{"uuid":"org-1"}]`))
+		case "/api/organizations/org-1/usage":
+			http.Redirect(w, r, "/login?returnTo=%2Fapi", http.StatusFound)
+		case "/login":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<!DOCTYPE html><title>Log in</title>"))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClaudeUsageClient("sk-test")
+	client.SetBaseURL(server.URL + "/api")
+
+	_, err := client.GetUsageLimits()
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("GetUsageLimits() error = %v, want ErrAuthFailed", err)
+	}
+	if CategoryOf(err) != CategoryAuth {
+		t.Errorf("GetUsageLimits() category = %q, want auth", CategoryOf(err))
+	}
+	if client.OrganizationID() != "org-1" {
+		t.Errorf("OrganizationID() = %q, want the redirect to the organizations list followed", client.OrganizationID())
+	}
+	if err := client.TestSession(); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("TestSession() error = %v, want ErrSessionExpired", err)
+	}
+}
+
+func TestSetIPVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"five_hour":{"utilization":42,"resets_at":null}}`))
+	}))
+	defer server.Close()
+
+	// httptest listens on 127.0.0.1, which only IPv4 can reach
+	for _, tt := range []struct {
+		version IPVersion
+		wantErr bool
+	}{
+		{IPAny, false},
+		{IPv4, false},
+		{IPv6, true},
+	} {
+		client := NewClaudeUsageClientWithOrg("sk-test", "org-1")
+		client.SetBaseURL(server.URL)
+		if err := client.SetIPVersion(tt.version); err != nil {
+			t.Fatalf("SetIPVersion(%q) error = %v", tt.version, err)
+		}
+		if _, err := client.GetUsageLimits(); (err != nil) != tt.wantErr {
+			t.Errorf("GetUsageLimits() with IP version %q error = %v, wantErr %v", tt.version, err, tt.wantErr)
+		}
+	}
+
+	client := NewClaudeUsageClient("sk-test")
+	if err := client.SetIPVersion("ipv4"); err == nil {
+		t.Error("SetIPVersion(\"ipv4\") succeeded, want an error")
+	}
+	if client.httpOptions.IPVersion != IPAny {
+		t.Errorf("SetIPVersion() with an invalid version changed it to %q", client.httpOptions.IPVersion)
+	}
+}
+
+func TestSetTimeout(t *testing.T) {
+	client := NewClaudeUsageClient("sk-test")
+	client.SetTimeout(time.Second)
+	if client.httpClient.Timeout != time.Second {
+		t.Errorf("timeout = %v, want %v", client.httpClient.Timeout, time.Second)
+	}
+	for _, timeout := range []time.Duration{time.Minute, 0, -time.Second} {
+		client.SetTimeout(timeout)
+		if client.httpClient.Timeout != time.Second {
+			t.Errorf("SetTimeout(%v) changed timeout to %v", timeout, client.httpClient.Timeout)
+		}
+	}
+}
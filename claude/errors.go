@@ -0,0 +1,76 @@
+// errors.go - Categorized errors for failed API requests
+
+package claude
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrorCategory says what kind of failure a ClientError is, for callers
+// that branch on it rather than on message text
+type ErrorCategory string
+
+const (
+	CategoryAuth      ErrorCategory = "auth"       // Session rejected or expired (ErrAuthFailed)
+	CategoryBlocked   ErrorCategory = "blocked"    // Cloudflare challenge or HTML page (ErrBlocked)
+	CategoryNetwork   ErrorCategory = "network"    // No response: unreachable, timed out, or canceled
+	CategoryRateLimit ErrorCategory = "rate-limit" // Status 429
+	CategoryNotFound  ErrorCategory = "not-found"  // Status 404, or no organization in the response
+	CategoryParse     ErrorCategory = "parse"      // A response that isn't the JSON expected
+	CategoryServer    ErrorCategory = "server"     // Any other unexpected status
+	CategoryConfig    ErrorCategory = "config"     // The request couldn't be built, e.g. a malformed base URL
+)
+
+// ClientError is a failed request with its category. It wraps the
+// underlying error, so errors.Is still matches ErrAuthFailed, ErrBlocked
+// and ErrOrgIDNotFound, and its message is the underlying one.
+type ClientError struct {
+	Category   ErrorCategory
+	StatusCode int // 0 if no response arrived
+	Err        error
+}
+
+func (e *ClientError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ClientError) Unwrap() error {
+	return e.Err
+}
+
+// CategoryOf returns the category of the ClientError in err's chain, or ""
+// if there is none
+func CategoryOf(err error) ErrorCategory {
+	var clientErr *ClientError
+	if errors.As(err, &clientErr) {
+		return clientErr.Category
+	}
+	return ""
+}
+
+// Helper function to wrap err in a ClientError
+func newClientError(category ErrorCategory, statusCode int, err error) *ClientError {
+	return &ClientError{Category: category, StatusCode: statusCode, Err: err}
+}
+
+// Helper function to categorize a failed http.Client.Do. A redirect to a
+// login page fails there too, as ErrAuthFailed.
+func requestError(err error) *ClientError {
+	if errors.Is(err, ErrAuthFailed) {
+		return newClientError(CategoryAuth, 0, err)
+	}
+	return newClientError(CategoryNetwork, 0, err)
+}
+
+// Helper function to categorize an unexpected status with no sentinel error
+func statusCategory(statusCode int) ErrorCategory {
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return CategoryRateLimit
+	case http.StatusNotFound:
+		return CategoryNotFound
+	default:
+		return CategoryServer
+	}
+}
@@ -0,0 +1,49 @@
+// redact.go - Masking session keys before they reach logs or output
+
+package claude
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// Kept from a masked key so two keys can be told apart
+	redactedPrefixLen = 10
+	redactedSuffixLen = 4
+
+	// Keys shorter than this are masked entirely, since the prefix and
+	// suffix would give away most of them
+	minPartialRedactLen = 24
+
+	redactedKey = "[redacted]"
+)
+
+// Session keys and OAuth tokens as claude.ai issues them. A trailing "…"
+// marks a key that's already masked.
+var sessionKeyPattern = regexp.MustCompile(`sk-ant-[A-Za-z0-9_-]+…?`)
+
+// RedactSessionKey masks a session key for display, keeping only a prefix
+// and suffix, e.g. "sk-ant-sid…x7Qz". Every place that might show a key
+// goes through here or RedactSecrets.
+func RedactSessionKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	if len(key) < minPartialRedactLen {
+		return redactedKey
+	}
+	return key[:redactedPrefixLen] + "…" + key[len(key)-redactedSuffixLen:]
+}
+
+// RedactSecrets masks every session key found in s, e.g. a log line or a
+// request trace. Keys already masked are left alone, so text can safely pass
+// through it twice.
+func RedactSecrets(s string) string {
+	return sessionKeyPattern.ReplaceAllStringFunc(s, func(key string) string {
+		if strings.HasSuffix(key, "…") {
+			return key
+		}
+		return RedactSessionKey(key)
+	})
+}
@@ -1,78 +1,516 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	configFilePermissions = 0600 // Owner read/write only
+	configPathEnv         = "CLAUDE_MONITOR_CONFIG"
+
+	// Bump when a change needs a migration step in migrateConfig
+	currentConfigVersion = 1
+
+	// Failed fetches in a row before a notification
+	defaultNotifyAfterFailures = 5
+
+	// Failed fetches in a row before the menu bar shows an error
+	defaultErrorAfterFailures = 2
+
+	// Saved sessions older than this get a re-login hint
+	defaultSessionWarnAfterDays = 25
 )
 
 type Config struct {
-	SessionKey       string     `json:"sessionKey,omitempty"`
-	OrganizationID   string     `json:"organizationId,omitempty"`
-	SavedAt          *time.Time `json:"savedAt,omitempty"`
-	MenuBarIndicator string     `json:"menuBarIndicator"`
+	Version                  int         `json:"version"`
+	SessionKey               string      `json:"sessionKey,omitempty"`
+	SessionKeyCommand        string      `json:"sessionKeyCommand,omitempty"` // Prints the session key, e.g. "op read ..."
+	OrganizationID           string      `json:"organizationId,omitempty"`
+	AccountName              string      `json:"accountName,omitempty"`
+	AccountEmail             string      `json:"accountEmail,omitempty"`
+	AccountPlan              string      `json:"accountPlan,omitempty"` // e.g. "Max 20x", cached at login
+	SavedAt                  *time.Time  `json:"savedAt,omitempty"`
+	AccountCheckedAt         *time.Time  `json:"accountCheckedAt,omitempty"`
+	AuthMode                 string      `json:"authMode,omitempty"` // "cookie" (default) or "bearer"
+	MenuBarIndicator         string      `json:"menuBarIndicator"`
+	MenuBarStyle             string      `json:"menuBarStyle,omitempty"`   // "percent" (default) or "bar"
+	MenuBarDisplay           string      `json:"menuBarDisplay,omitempty"` // "text" (default), "icon" or "iconAndText"
+	DisplayLimits            []string    `json:"displayLimits,omitempty"`  // Limit keys to show, in order; empty shows all
+	ShowRemaining            bool        `json:"showRemaining,omitempty"`
+	NoSessionLabel           string      `json:"noSessionLabel,omitempty"`      // 5-hour limit before a session starts
+	UnusedLabel              string      `json:"unusedLabel,omitempty"`         // Weekly limit not used since it reset
+	UtilizationRounding      string      `json:"utilizationRounding,omitempty"` // "round" (default), "floor" or "ceil"
+	FixedWidthMenuBar        bool        `json:"fixedWidthMenuBar,omitempty"`
+	UseEmoji                 bool        `json:"useEmoji"`
+	CombineWeeklyResets      bool        `json:"combineWeeklyResets"`
+	WeeklyDaysAfterHours     int         `json:"weeklyDaysAfterHours,omitempty"` // Weekly countdowns this long or longer show days; 0 disables
+	EnableSocket             bool        `json:"enableSocket,omitempty"`
+	EnableFifo               bool        `json:"enableFifo,omitempty"`
+	Headless                 bool        `json:"headless,omitempty"`
+	Paused                   bool        `json:"paused,omitempty"`
+	DisableKeepAlives        bool        `json:"disableKeepAlives,omitempty"`
+	IPVersion                string      `json:"ipVersion,omitempty"` // "4" or "6"; empty uses both
+	CAFile                   string      `json:"caFile,omitempty"`    // PEM bundle trusted besides the system CAs
+	ClientCertFile           string      `json:"clientCertFile,omitempty"`
+	ClientKeyFile            string      `json:"clientKeyFile,omitempty"`
+	UserAgent                string      `json:"userAgent,omitempty"`
+	RecordHistory            bool        `json:"recordHistory,omitempty"`
+	NotifyThresholds         []float64   `json:"notifyThresholds,omitempty"`
+	NotifyLimits             []string    `json:"notifyLimits,omitempty"` // Limit keys that notify; empty means all
+	NotifyCooldownMinutes    int         `json:"notifyCooldownMinutes,omitempty"`
+	NotifySound              string      `json:"notifySound,omitempty"`
+	NotifyOnReset            bool        `json:"notifyOnReset,omitempty"`
+	NotifyBeforeResetMinutes int         `json:"notifyBeforeResetMinutes,omitempty"`
+	NotifyBeforeCapMinutes   int         `json:"notifyBeforeCapMinutes,omitempty"`
+	NotifyAfterFailures      int         `json:"notifyAfterFailures"`           // 0 disables
+	ErrorAfterFailures       int         `json:"errorAfterFailures"`            // 0 or 1 shows errors at once
+	SessionWarnAfterDays     int         `json:"sessionWarnAfterDays"`          // 0 disables
+	CriticalHookCommand      string      `json:"criticalHookCommand,omitempty"` // Shell command run when a limit turns red
+	SnoozeUntil              *time.Time  `json:"snoozeUntil,omitempty"`
+	QuietHours               *QuietHours `json:"quietHours,omitempty"`
+	AdaptivePolling          bool        `json:"adaptivePolling,omitempty"`
+	MinRefreshSeconds        int         `json:"minRefreshSeconds,omitempty"`
+	MaxRefreshSeconds        int         `json:"maxRefreshSeconds,omitempty"`
+	MaxDailyRequests         int         `json:"maxDailyRequests,omitempty"` // Scheduled fetches per local day; 0 means no cap
+	StaleAfterMinutes        int         `json:"staleAfterMinutes,omitempty"`
+	ResetPrecisionMinutes    int         `json:"resetPrecisionMinutes,omitempty"`
+	// Per-limit reset time precision in minutes, keyed by limit type (e.g. "five_hour")
+	ResetPrecision map[string]int `json:"resetPrecision,omitempty"`
+	// Per-limit color thresholds, keyed by limit type (e.g. "seven_day_opus")
+	ColorThresholds map[string]ColorThresholds `json:"colorThresholds,omitempty"`
+	// Glyphs for the low/mid/high usage indicators, replacing the colored dots
+	ColorIndicators ColorIndicators `json:"colorIndicators,omitzero"`
 }
 
-func GetConfigPath() string {
-	homeDir, _ := os.UserHomeDir()
-	return filepath.Join(homeDir, ".claude-monitor-lite.json")
+// ColorIndicators are the glyphs shown for low, mid and high usage
+type ColorIndicators struct {
+	Low  string `json:"low"`
+	Mid  string `json:"mid"`
+	High string `json:"high"`
 }
 
-func LoadConfig() Config {
-	defaultConfig := Config{MenuBarIndicator: "currentSession"}
+var (
+	emojiColorIndicators = ColorIndicators{Low: "🟢", Mid: "🟡", High: "🔴"}
+	textColorIndicators  = ColorIndicators{Low: "[OK]", Mid: "[WARN]", High: "[CRIT]"}
+)
+
+// errConfigEmpty means the config file exists but holds nothing, e.g. after
+// a crash truncated it
+var errConfigEmpty = errors.New("config file is empty")
+
+// Config problems are logged once, not on every LoadConfig
+var brokenConfigWarning sync.Once
+
+// Serializes read-modify-write of the config file, so concurrent saves (menu
+// clicks, account lookups) can't overwrite each other's changes
+var configFileMutex sync.Mutex
 
-	data, err := os.ReadFile(GetConfigPath())
+// ColorThresholds overrides the yellow/red percentages for one limit.
+// A zero field keeps the global default.
+type ColorThresholds struct {
+	Yellow float64 `json:"yellow,omitempty"`
+	Red    float64 `json:"red,omitempty"`
+}
+
+// defaultConfig returns the settings used for keys missing from the file
+func defaultConfig() Config {
+	return Config{
+		MenuBarIndicator:     "currentSession",
+		UseEmoji:             true,
+		CombineWeeklyResets:  true,
+		NotifyAfterFailures:  defaultNotifyAfterFailures,
+		ErrorAfterFailures:   defaultErrorAfterFailures,
+		SessionWarnAfterDays: defaultSessionWarnAfterDays,
+	}
+}
+
+// LoadConfig reads the config file. A missing file (first run) gives the
+// defaults quietly; an empty or corrupt one gives the defaults with a warning.
+func LoadConfig() Config {
+	path := GetConfigPath()
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return defaultConfig
+		if !os.IsNotExist(err) {
+			warnBrokenConfig(path, err)
+		}
+		return defaultConfig()
 	}
 
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		return defaultConfig
+	config := defaultConfig()
+	if err := parseConfig(data, &config); err != nil {
+		warnBrokenConfig(path, err)
+		return defaultConfig()
 	}
 
 	if config.MenuBarIndicator == "" {
 		config.MenuBarIndicator = "currentSession"
 	}
+	validateColorIndicators(&config)
+	validateQuietHours(&config)
+	config.DisplayLimits = validateLimitKeys("displayLimits", config.DisplayLimits)
+	config.NotifyLimits = validateLimitKeys("notifyLimits", config.NotifyLimits)
 
+	migrateConfig(&config)
 	return config
 }
 
+// validateColorIndicators fills any empty custom indicator with the default,
+// so a partial setting never leaves the menu bar without a marker
+func validateColorIndicators(config *Config) {
+	custom := &config.ColorIndicators
+	if *custom == (ColorIndicators{}) {
+		return
+	}
+
+	defaults := emojiColorIndicators
+	if !config.UseEmoji {
+		defaults = textColorIndicators
+	}
+	for _, slot := range []struct {
+		name     string
+		glyph    *string
+		fallback string
+	}{
+		{"low", &custom.Low, defaults.Low},
+		{"mid", &custom.Mid, defaults.Mid},
+		{"high", &custom.High, defaults.High},
+	} {
+		if strings.TrimSpace(*slot.glyph) == "" {
+			log.Printf("Warning: colorIndicators.%s is empty, using %q\n", slot.name, slot.fallback)
+			*slot.glyph = slot.fallback
+		}
+	}
+}
+
+// validateLimitKeys drops unknown or repeated limit keys from the setting
+// named setting. If none are left it returns nil, which means every limit.
+func validateLimitKeys(setting string, keys []string) []string {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	var valid []string
+	for _, key := range keys {
+		if _, ok := findLimitKind(key); !ok {
+			log.Printf("Warning: %s has unknown limit %q, ignoring it\n", setting, key)
+			continue
+		}
+		if slices.Contains(valid, key) {
+			log.Printf("Warning: %s lists %q more than once, ignoring the repeat\n", setting, key)
+			continue
+		}
+		valid = append(valid, key)
+	}
+	if len(valid) == 0 {
+		log.Printf("Warning: %s has no known limits, using all\n", setting)
+	}
+	return valid
+}
+
+// migrateConfig upgrades a config read from an older version in place.
+// Configs from newer versions are left alone.
+func migrateConfig(config *Config) {
+	if config.Version < 1 {
+		// Version 0 predates versioning; new keys already take their
+		// defaults from defaultConfig, so only the version changes
+		config.Version = 1
+	}
+}
+
+// parseConfig decodes a config file's contents into config
+func parseConfig(data []byte, config *Config) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return errConfigEmpty
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return fmt.Errorf("config file is corrupt: %w", err)
+	}
+	return nil
+}
+
+// checkConfigFile reports a config file that exists but can't be used.
+// A missing file is fine: that's a first run.
+func checkConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return parseConfig(data, &Config{})
+}
+
+// warnBrokenConfig logs that the config at path is unusable, pointing at the
+// newest backup that could replace it
+func warnBrokenConfig(path string, err error) {
+	brokenConfigWarning.Do(func() {
+		log.Printf("Warning: %s: %v. Using defaults, so the session is lost until it's fixed or you log in again.\n", path, err)
+		if backup := latestConfigBackup(path); backup != "" {
+			log.Printf("Warning: A backup is available at %s. Run 'claude-monitor-lite' to restore it.\n", backup)
+		}
+	})
+}
+
+// latestConfigBackup returns the newest backup of the config at path that
+// parses, or "" if there is none
+func latestConfigBackup(path string) string {
+	backups, _ := filepath.Glob(path + ".bak-*")
+	// The timestamp suffix sorts chronologically
+	slices.Sort(backups)
+	for _, backup := range slices.Backward(backups) {
+		if data, err := os.ReadFile(backup); err == nil && parseConfig(data, &Config{}) == nil {
+			return backup
+		}
+	}
+	return ""
+}
+
+// RestoreConfigBackup replaces the config file with a backup
+func RestoreConfigBackup(backup string) error {
+	path, err := ResolveConfigPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, configFilePermissions, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// PurgeConfig removes the config file entirely for a clean uninstall
+func PurgeConfig() error {
+	err := os.Remove(GetConfigPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// BackupConfig copies the config file to a timestamped backup next to it.
+// Returns the backup path, or "" if there is no config to back up.
+func BackupConfig() (string, error) {
+	path, err := ResolveConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return writeConfigBackup(path, data)
+}
+
+// BackupPreferences backs up the config like BackupConfig, but without the
+// session and account fields, so nothing that signs in stays on disk.
+// Returns "" if there is no config, or it's too broken to hold preferences.
+func BackupPreferences() (string, error) {
+	path, err := ResolveConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	config := defaultConfig()
+	if parseConfig(data, &config) != nil {
+		return "", nil
+	}
+	clearSessionFields(&config)
+	preferences, err := mergeConfigJSON(data, config)
+	if err != nil {
+		return "", err
+	}
+	return writeConfigBackup(path, preferences)
+}
+
+// writeConfigBackup writes data to a timestamped backup of the config at
+// path and removes any older backups, so only the newest is kept
+func writeConfigBackup(path string, data []byte) (string, error) {
+	older, _ := filepath.Glob(path + ".bak-*")
+
+	backupPath := path + ".bak-" + time.Now().Format("20060102-150405")
+	if err := os.WriteFile(backupPath, data, configFilePermissions); err != nil {
+		return "", err
+	}
+
+	for _, backup := range older {
+		if backup == backupPath {
+			continue
+		}
+		if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: Failed to remove old config backup: %v\n", err)
+		}
+	}
+	return backupPath, nil
+}
+
 func SaveConfig(config Config) error {
-	data, err := json.MarshalIndent(config, "", "  ")
+	path, err := ResolveConfigPath()
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(GetConfigPath(), data, configFilePermissions)
+
+	configFileMutex.Lock()
+	defer configFileMutex.Unlock()
+	return writeConfig(path, config)
 }
 
 // SaveConfigPreservingSession updates only menuBarIndicator, preserving session fields
 func SaveConfigPreservingSession(menuBarIndicator string) error {
+	return UpdateConfig(func(config *Config) {
+		config.MenuBarIndicator = menuBarIndicator
+	})
+}
+
+// UpdateConfig applies mutate to the config file on disk, preserving all other fields.
+// Concurrent updates run one at a time, each seeing the ones before it.
+func UpdateConfig(mutate func(*Config)) error {
+	path, err := ResolveConfigPath()
+	if err != nil {
+		return err
+	}
+
+	configFileMutex.Lock()
+	defer configFileMutex.Unlock()
+
 	// Read the current file to preserve session fields
-	path := GetConfigPath()
 	existingData, err := os.ReadFile(path)
 
-	var existing Config
+	existing := defaultConfig()
 	if err == nil {
 		// File exists, parse it to preserve session fields
-		// If unmarshal fails, existing will be zero-valued (safe)
-		if unmarshalErr := json.Unmarshal(existingData, &existing); unmarshalErr != nil {
-			// On parse error, start fresh with just the menuBarIndicator
-			existing = Config{}
+		if parseErr := parseConfig(existingData, &existing); parseErr != nil {
+			// On parse error, start fresh with just the mutated fields, but
+			// keep a copy of a corrupt file: it may still hold the session
+			if !errors.Is(parseErr, errConfigEmpty) {
+				if _, err := BackupConfig(); err != nil {
+					return fmt.Errorf("failed to back up corrupt config: %w", err)
+				}
+			}
+			existing = defaultConfig()
 		}
 	}
 
-	// Only update the menuBarIndicator
-	existing.MenuBarIndicator = menuBarIndicator
+	migrateConfig(&existing)
+	mutate(&existing)
 
-	data, err := json.MarshalIndent(existing, "", "  ")
+	return writeConfig(path, existing)
+}
+
+// writeConfig writes config to path, keeping any keys in the existing file
+// that this version doesn't know about (e.g. written by a newer release)
+func writeConfig(path string, config Config) error {
+	// A missing or unreadable file just has no extra keys to keep
+	existing, _ := os.ReadFile(path)
+	data, err := mergeConfigJSON(existing, config)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), dataDirPermissions); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, configFilePermissions, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// mergeConfigJSON encodes config over the keys in existing, a config file's
+// contents, keeping the ones this version doesn't know about
+func mergeConfigJSON(existing []byte, config Config) ([]byte, error) {
+	config.Version = max(config.Version, currentConfigVersion)
+
+	known, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]json.RawMessage)
+	// A corrupt file simply has nothing worth preserving
+	json.Unmarshal(existing, &merged)
+
+	// Drop every known key first so cleared omitempty fields don't survive
+	for _, key := range configKeys() {
+		delete(merged, key)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(known, &fields); err != nil {
+		return nil, err
+	}
+	maps.Copy(merged, fields)
+
+	return json.MarshalIndent(merged, "", "  ")
+}
+
+// writeFileAtomic writes to a temp file in the same directory and renames it
+// over path, so a crash mid-write leaves either the old or the new file,
+// never a truncated one. A symlinked path is written through to its target.
+func writeFileAtomic(path string, perm os.FileMode, write func(io.Writer) error) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, configFilePermissions)
+	// Clean up on any failure; after a successful rename this is a no-op
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// configKeys returns the JSON key of every Config field
+func configKeys() []string {
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, t.NumField())
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		keys = append(keys, name)
+	}
+	return keys
 }
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConfigPreservesUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv(configPathEnv, path)
+
+	original := `{
+  "sessionKey": "sk-test",
+  "menuBarIndicator": "weeklyAll",
+  "futureSetting": {"nested": [1, 2, 3]},
+  "futureFlag": true
+}`
+	if err := os.WriteFile(path, []byte(original), configFilePermissions); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SaveConfigPreservingSession("weeklyOpus"); err != nil {
+		t.Fatalf("SaveConfigPreservingSession() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("saved config is not valid JSON: %v", err)
+	}
+
+	if got := string(raw["futureFlag"]); got != "true" {
+		t.Errorf("futureFlag = %s, want true", got)
+	}
+	var nested struct{ Nested []int }
+	if err := json.Unmarshal(raw["futureSetting"], &nested); err != nil || len(nested.Nested) != 3 {
+		t.Errorf("futureSetting = %s, want preserved", raw["futureSetting"])
+	}
+
+	config := LoadConfig()
+	if config.SessionKey != "sk-test" || config.MenuBarIndicator != "weeklyOpus" {
+		t.Errorf("LoadConfig() = %+v, want session kept and indicator updated", config)
+	}
+	if config.Version != currentConfigVersion {
+		t.Errorf("Version = %d, want %d", config.Version, currentConfigVersion)
+	}
+}
+
+func TestConfigClearedFieldsAreRemoved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv(configPathEnv, path)
+
+	if err := os.WriteFile(path, []byte(`{"sessionKey": "sk-test", "organizationId": "org"}`), configFilePermissions); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ClearAuthSession(); err != nil {
+		t.Fatalf("ClearAuthSession() error = %v", err)
+	}
+
+	if config := LoadConfig(); config.SessionKey != "" || config.OrganizationID != "" {
+		t.Errorf("LoadConfig() = %+v, want session fields cleared", config)
+	}
+}
+
+func TestCheckConfigFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name      string
+		exists    bool
+		content   string
+		wantErr   bool
+		wantEmpty bool
+	}{
+		{"missing", false, "", false, false},
+		{"valid", true, `{"sessionKey": "sk-test"}`, false, false},
+		{"empty", true, "", true, true},
+		{"whitespace", true, " \n\t\n", true, true},
+		{"truncated", true, `{"sessionKey": "sk-te`, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if tt.exists {
+				if err := os.WriteFile(path, []byte(tt.content), configFilePermissions); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := checkConfigFile(path)
+			if (err != nil) != tt.wantErr || errors.Is(err, errConfigEmpty) != tt.wantEmpty {
+				t.Errorf("checkConfigFile() = %v, want error %v (empty %v)", err, tt.wantErr, tt.wantEmpty)
+			}
+		})
+	}
+}
+
+func TestLatestConfigBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(name, content string) {
+		if err := os.WriteFile(name, []byte(content), configFilePermissions); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := latestConfigBackup(path); got != "" {
+		t.Errorf("latestConfigBackup() with no backups = %q, want none", got)
+	}
+
+	write(path+".bak-20250601-090000", `{"sessionKey": "sk-old"}`)
+	write(path+".bak-20250610-090000", `{"sessionKey": "sk-new"}`)
+	// Newest, but as broken as the file it would replace
+	write(path+".bak-20250612-090000", `{"sessionKey": `)
+
+	if got, want := latestConfigBackup(path), path+".bak-20250610-090000"; got != want {
+		t.Errorf("latestConfigBackup() = %q, want %q", got, want)
+	}
+}
+
+func TestUpdateConfigBacksUpCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv(configPathEnv, path)
+
+	corrupt := `{"sessionKey": "sk-test", "menuBar`
+	if err := os.WriteFile(path, []byte(corrupt), configFilePermissions); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveConfigPreservingSession("weeklyAll"); err != nil {
+		t.Fatalf("SaveConfigPreservingSession() error = %v", err)
+	}
+
+	backups, _ := filepath.Glob(path + ".bak-*")
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want one copy of the corrupt file", backups)
+	}
+	if data, _ := os.ReadFile(backups[0]); string(data) != corrupt {
+		t.Errorf("backup = %q, want the corrupt file's contents", data)
+	}
+	if config := LoadConfig(); config.MenuBarIndicator != "weeklyAll" {
+		t.Errorf("LoadConfig() = %+v, want the update saved", config)
+	}
+}
+
+func TestUpdateConfigConcurrent(t *testing.T) {
+	t.Setenv(configPathEnv, filepath.Join(t.TempDir(), "config.json"))
+
+	// Each update reads the file the one before it wrote, so none is lost
+	const updates = 20
+	var wg sync.WaitGroup
+	for range updates {
+		wg.Go(func() {
+			if err := UpdateConfig(func(config *Config) { config.NotifyCooldownMinutes++ }); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := LoadConfig().NotifyCooldownMinutes; got != updates {
+		t.Errorf("after %d concurrent increments NotifyCooldownMinutes = %d", updates, got)
+	}
+}
+
+func TestBackupPreferences(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv(configPathEnv, path)
+
+	config := `{"sessionKey": "sk-test", "organizationId": "org-1", "accountEmail": "a@example.com", "menuBarIndicator": "weeklyAll", "fromNewerRelease": true}`
+	if err := os.WriteFile(path, []byte(config), configFilePermissions); err != nil {
+		t.Fatal(err)
+	}
+	older := path + ".bak-20250601-090000"
+	if err := os.WriteFile(older, []byte(`{"sessionKey": "sk-old"}`), configFilePermissions); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := BackupPreferences()
+	if err != nil || backup == "" {
+		t.Fatalf("BackupPreferences() = %q, %v, want a backup", backup, err)
+	}
+	data, _ := os.ReadFile(backup)
+	for _, key := range []string{"sk-test", "org-1", "a@example.com"} {
+		if strings.Contains(string(data), key) {
+			t.Errorf("backup = %s, want no %q", data, key)
+		}
+	}
+	for _, key := range []string{"weeklyAll", "fromNewerRelease"} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("backup = %s, want %q kept", data, key)
+		}
+	}
+	if backups, _ := filepath.Glob(path + ".bak-*"); len(backups) != 1 {
+		t.Errorf("backups = %v, want only the new one", backups)
+	}
+}
+
+func TestMigrateConfig(t *testing.T) {
+	config := Config{}
+	migrateConfig(&config)
+	if config.Version != 1 {
+		t.Errorf("migrated Version = %d, want 1", config.Version)
+	}
+
+	newer := Config{Version: currentConfigVersion + 1}
+	migrateConfig(&newer)
+	if newer.Version != currentConfigVersion+1 {
+		t.Errorf("newer Version = %d, want unchanged", newer.Version)
+	}
+}
+
+func TestWriteFileAtomicInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	original := `{"sessionKey": "sk-test"}`
+	if err := os.WriteFile(path, []byte(original), configFilePermissions); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write half the new contents, then fail as if the process died mid-write
+	interrupted := errors.New("interrupted")
+	err := writeFileAtomic(path, configFilePermissions, func(w io.Writer) error {
+		w.Write([]byte(`{"sessionKey": "sk-n`))
+		return interrupted
+	})
+	if !errors.Is(err, interrupted) {
+		t.Fatalf("writeFileAtomic() error = %v, want %v", err, interrupted)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != original {
+		t.Errorf("config = %q after interrupted write, want original %q", data, original)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the config (temp file left behind?)", len(entries))
+	}
+}
+
+func TestWriteFileAtomicReplaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv(configPathEnv, path)
+
+	if err := SaveConfig(Config{SessionKey: "sk-test"}); err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+	if err := SaveConfigPreservingSession("weeklyAll"); err != nil {
+		t.Fatalf("SaveConfigPreservingSession() error = %v", err)
+	}
+
+	config := LoadConfig()
+	if config.SessionKey != "sk-test" || config.MenuBarIndicator != "weeklyAll" {
+		t.Errorf("LoadConfig() = %+v, want both saves applied", config)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != configFilePermissions {
+		t.Errorf("config permissions = %o, want %o", perm, configFilePermissions)
+	}
+}
+
+func TestValidateColorIndicators(t *testing.T) {
+	tests := []struct {
+		name     string
+		useEmoji bool
+		custom   ColorIndicators
+		want     ColorIndicators
+	}{
+		{"unset stays unset", true, ColorIndicators{}, ColorIndicators{}},
+		{"complete set kept", true, ColorIndicators{"●", "■", "▲"}, ColorIndicators{"●", "■", "▲"}},
+		{"empty slot gets emoji default", true, ColorIndicators{"●", "", "▲"}, ColorIndicators{"●", "🟡", "▲"}},
+		{"blank slot gets text default", false, ColorIndicators{"●", "■", " "}, ColorIndicators{"●", "■", "[CRIT]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{UseEmoji: tt.useEmoji, ColorIndicators: tt.custom}
+			validateColorIndicators(&config)
+			if config.ColorIndicators != tt.want {
+				t.Errorf("ColorIndicators = %+v, want %+v", config.ColorIndicators, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLimitKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits []string
+		want   []string
+	}{
+		{"unset stays unset", nil, nil},
+		{"order kept", []string{"seven_day_opus", "five_hour"}, []string{"seven_day_opus", "five_hour"}},
+		{"unknown dropped", []string{"five_hour", "daily"}, []string{"five_hour"}},
+		{"repeat dropped", []string{"seven_day", "seven_day"}, []string{"seven_day"}},
+		{"nothing valid clears", []string{"daily"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateLimitKeys("displayLimits", tt.limits); !slices.Equal(got, tt.want) {
+				t.Errorf("validateLimitKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,16 +3,70 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
 )
 
 const (
-	daemonStartupDelay = 100 * time.Millisecond
+	daemonStartupDelay  = 100 * time.Millisecond
+	systrayReadyTimeout = 5 * time.Second
+	logFilePermissions  = 0600 // Owner read/write only
+	maxLogSize          = 1 << 20
+
+	// Logged at startup and searched for by the diagnose command
+	systrayReadyMessage    = "Menu bar ready"
+	systrayNotReadyMessage = "Menu bar not ready"
+
+	// Logged when fetches start or stop failing, for the diagnose command
+	apiOKMessage     = "Usage fetch OK"
+	apiFailedMessage = "Usage fetch failed"
 )
 
+// Closed by onReady once systray has initialized
+var systrayReady = make(chan struct{})
+
+// Opened by onReady once the menu is built. Fetches run on their own
+// goroutines, so anything drawing a result waits on it: a title set before
+// then could be dropped or overwritten, leaving "Loading..." up.
+var menuBuilt = newReadyGate()
+
+// readyGate blocks callers until it's opened, then lets everyone through
+type readyGate struct {
+	ready chan struct{}
+	once  sync.Once
+}
+
+func newReadyGate() *readyGate {
+	return &readyGate{ready: make(chan struct{})}
+}
+
+// open releases every waiter, present and future. Only the first call counts.
+func (g *readyGate) open() {
+	g.once.Do(func() { close(g.ready) })
+}
+
+// wait blocks until the gate opens, or returns false if ctx ends first
+func (g *readyGate) wait(ctx context.Context) bool {
+	select {
+	case <-g.ready:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 // daemonize starts the process in background if not already daemonized
 func daemonize() {
 	// Check if we're already the background process
@@ -25,7 +79,7 @@ func daemonize() {
 	executable, err := os.Executable()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to get executable path: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	// Start a new process in background
@@ -40,16 +94,81 @@ func daemonize() {
 	// Start the background process
 	if err := cmd.Start(); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to start background process: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
-	fmt.Printf("Claude Monitor Lite started in background (PID: %d)\n", cmd.Process.Pid)
-	fmt.Println("Click the menu bar icon to view usage.")
-	fmt.Println("Quit via the menu bar to stop.")
+	infof("Claude Monitor Lite started in background (PID: %d)\n", cmd.Process.Pid)
+	info("Click the menu bar icon to view usage.")
+	info("Quit via the menu bar to stop.")
 
 	// Wait a moment for the child to create its PID file
 	time.Sleep(daemonStartupDelay)
 
 	// Exit the parent process
-	os.Exit(0)
+	os.Exit(exitOK)
+}
+
+// processExecutable returns the executable path of a running process
+func processExecutable(pid int) (string, error) {
+	if runtime.GOOS == "linux" {
+		path, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+		// The binary may have been replaced since the process started
+		return strings.TrimSuffix(path, " (deleted)"), err
+	}
+
+	// macOS and other Unixes: comm is the full executable path
+	out, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "comm=").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// isSameExecutable reports whether pid is running the given executable.
+// When the executable can't be determined (e.g. on Windows), the PID is trusted.
+func isSameExecutable(pid int, executable string) bool {
+	actual, err := processExecutable(pid)
+	if err != nil || actual == "" {
+		return true
+	}
+	return filepath.Base(actual) == filepath.Base(executable)
+}
+
+// setupDaemonLog sends log output to the log file, since the daemon has no terminal.
+// A log over maxLogSize is rotated to .old on startup.
+func setupDaemonLog() {
+	if info, err := os.Stat(logFile); err == nil && info.Size() > maxLogSize {
+		os.Rename(logFile, logFile+".old")
+	}
+
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, logFilePermissions)
+	if err != nil {
+		return
+	}
+	log.SetOutput(redactingWriter{f})
+}
+
+// redactingWriter masks session keys in everything written through it, so
+// the log never holds a full key whatever path one took into a message
+type redactingWriter struct {
+	w io.Writer
+}
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, claude.RedactSecrets(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// watchSystrayReady logs whether the menu bar came up, since a failed
+// systray init otherwise leaves a running daemon with no visible UI
+func watchSystrayReady() {
+	select {
+	case <-systrayReady:
+		log.Println(systrayReadyMessage)
+	case <-time.After(systrayReadyTimeout):
+		log.Printf("%s after %s - the menu bar may be unavailable (no display or system tray?)\n",
+			systrayNotReadyMessage, systrayReadyTimeout)
+	}
 }
@@ -0,0 +1,274 @@
+// dashboard.go - Full-screen terminal dashboard (the dashboard command)
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+const (
+	dashboardRedrawInterval = time.Second // Keeps the countdowns live
+	minDashboardInterval    = 10 * time.Second
+	dashboardLabelWidth     = 16
+	minGaugeWidth           = 10
+
+	// Default size when the terminal can't be asked
+	defaultTerminalWidth  = 80
+	defaultTerminalHeight = 24
+)
+
+// ANSI escape sequences used by the dashboard
+const (
+	ansiAltScreen  = "\x1b[?1049h"
+	ansiMainScreen = "\x1b[?1049l"
+	ansiHideCursor = "\x1b[?25l"
+	ansiShowCursor = "\x1b[?25h"
+	ansiHome       = "\x1b[H"
+	ansiClearLine  = "\x1b[K"
+	ansiClearBelow = "\x1b[J"
+	ansiReset      = "\x1b[0m"
+	ansiBold       = "\x1b[1m"
+	ansiDim        = "\x1b[2m"
+	ansiRed        = "\x1b[31m"
+)
+
+var levelANSI = [...]string{
+	levelNone: ansiDim,
+	levelLow:  "\x1b[32m",
+	levelMid:  "\x1b[33m",
+	levelHigh: ansiRed,
+}
+
+// Sparkline levels, lowest first
+var sparklineBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// dashboardState is what the dashboard shows: the last successful fetch,
+// the last error, and the recorded history for the sparklines
+type dashboardState struct {
+	limits    *claude.UsageLimits
+	err       error
+	fetchedAt time.Time
+	history   []historyEntry
+}
+
+// fetchResult carries one background fetch back to the draw loop
+type fetchResult struct {
+	limits *claude.UsageLimits
+	err    error
+}
+
+func handleDashboard(args []string) {
+	fs := flag.NewFlagSet("dashboard", flag.ExitOnError)
+	interval := fs.Duration("interval", refreshInterval, "How often to fetch usage (at least 10s)")
+	sessionArgs := addSessionFlags(fs)
+	fs.Parse(args)
+
+	if *interval < minDashboardInterval {
+		fmt.Fprintf(os.Stderr, "Error: --interval must be at least %s\n", minDashboardInterval)
+		os.Exit(exitUsage)
+	}
+	if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		fmt.Fprintln(os.Stderr, "Error: the dashboard needs a terminal. Use 'claude-monitor-lite fetch' for scripts.")
+		os.Exit(exitUsage)
+	}
+
+	session, err := sessionArgs.session()
+	if err != nil {
+		if *sessionArgs.key == "" && *sessionArgs.keyFile == "" {
+			fmt.Fprintln(os.Stderr, "❌ Not authenticated. Run 'claude-monitor-lite login' first, or pass --session-key-file.")
+			os.Exit(exitNotAuthenticated)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	runDashboard(createClientFromSession(session), *interval)
+}
+
+// runDashboard draws the dashboard until Ctrl-C, fetching every interval.
+// The alternate screen keeps the shell's scrollback intact, and is left
+// however the loop ends.
+func runDashboard(client *claude.ClaudeUsageClient, interval time.Duration) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	enableTerminalEscapes()
+	fmt.Print(ansiAltScreen + ansiHideCursor)
+	defer fmt.Print(ansiReset + ansiShowCursor + ansiMainScreen)
+
+	resized := watchResize(ctx)
+	width, height := terminalSize()
+
+	// Buffered so a fetch finishing after Ctrl-C doesn't block forever
+	results := make(chan fetchResult, 1)
+	fetching := false
+	fetch := func() {
+		if fetching {
+			return
+		}
+		fetching = true
+		go func() {
+			limits, err := client.GetUsageLimitsContext(ctx)
+			results <- fetchResult{limits, err}
+		}()
+	}
+
+	var state dashboardState
+	fetch()
+	redraw := time.NewTicker(dashboardRedrawInterval)
+	defer redraw.Stop()
+	refetch := time.NewTicker(interval)
+	defer refetch.Stop()
+
+	for {
+		renderDashboard(os.Stdout, &state, width, height, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-redraw.C:
+		case <-resized:
+			width, height = terminalSize()
+		case <-refetch.C:
+			fetch()
+		case result := <-results:
+			fetching = false
+			state.err = result.err
+			if result.err == nil {
+				state.limits = result.limits
+				state.fetchedAt = time.Now()
+			}
+			// Missing when recordHistory is off; the sparklines say so
+			state.history, _ = readHistory(time.Now().Add(-recentHistoryWindow))
+		}
+	}
+}
+
+// renderDashboard draws one frame over the previous one. The frame is
+// written in one go, and each line clears what's left of the old one, so
+// redraws don't flicker.
+func renderDashboard(w io.Writer, state *dashboardState, width, height int, now time.Time) {
+	var lines []string
+	title := ansiBold + "Claude Usage" + ansiReset
+	if account := cachedAccount(); account != "" {
+		title += "  " + ansiDim + account + ansiReset
+	}
+	lines = append(lines, title, "")
+
+	gaugeWidth := max(minGaugeWidth, width-dashboardLabelWidth-30)
+	kinds := displayedLimitKinds()
+	// Each limit takes two lines with its sparkline, plus header and footer
+	showSparklines := height >= 4+len(kinds)*3
+
+	if state.limits == nil && state.err == nil {
+		lines = append(lines, "Loading...")
+	}
+	for _, kind := range kinds {
+		if state.limits == nil {
+			break
+		}
+		limit := kind.Get(state.limits)
+		label := fmt.Sprintf("%-*s", dashboardLabelWidth, kind.Label)
+		lines = append(lines, label+formatDashboardLimit(limit, kind.Key, gaugeWidth, now))
+		if !showSparklines {
+			continue
+		}
+
+		indent := strings.Repeat(" ", dashboardLabelWidth+1)
+		if len(state.history) == 0 {
+			lines = append(lines, indent+ansiDim+"no history (set recordHistory to keep it)"+ansiReset, "")
+			continue
+		}
+		spark := renderSparkline(state.history, kind.Get, now, recentHistoryWindow, gaugeWidth)
+		lines = append(lines, indent+ansiDim+spark+" last 5h"+ansiReset, "")
+	}
+
+	lines = append(lines, "")
+	footer := ansiDim + "Ctrl-C to quit" + ansiReset
+	if !state.fetchedAt.IsZero() {
+		footer = ansiDim + "Updated " + state.fetchedAt.Format("15:04:05") + " · " + ansiReset + footer
+	}
+	if state.err != nil {
+		footer = ansiRed + "Error: " + state.err.Error() + ansiReset + "  " + footer
+	}
+	lines = append(lines, footer)
+
+	var b strings.Builder
+	b.WriteString(ansiHome)
+	for _, line := range lines {
+		b.WriteString(line + ansiClearLine + "\n")
+	}
+	b.WriteString(ansiClearBelow)
+	io.WriteString(w, b.String())
+}
+
+// Helper function to format one limit's gauge, percentage and live countdown
+func formatDashboardLimit(limit *claude.UsageLimit, limitType string, gaugeWidth int, now time.Time) string {
+	if limit == nil {
+		return ansiDim + "--" + ansiReset
+	}
+
+	color := levelANSI[getUsageLevel(limit.Utilization, limitType)]
+	line := fmt.Sprintf("%s%s%s %s", color, renderGauge(displayPercent(limit.Utilization), gaugeWidth), ansiReset, formatPercent(limit.Utilization))
+	if idle := idleLimitLabel(limit, limitType); idle != "" {
+		return line + "  " + ansiDim + idle + ansiReset
+	}
+	if !limit.ResetsAtTime.IsZero() {
+		line += "  resets in " + formatCountdown(limit.ResetsAtTime.Sub(now))
+	}
+	return line
+}
+
+// Helper function to render a percentage as a gauge of width cells,
+// e.g. "█████░░░░░"
+func renderGauge(percent float64, width int) string {
+	filled := int(percent/100*float64(width) + 0.5)
+	filled = max(0, min(filled, width))
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+// Helper function to format a countdown to the second, e.g. "2h05m09s"
+func formatCountdown(d time.Duration) string {
+	d = max(0, d.Truncate(time.Second))
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	seconds := int(d.Seconds()) % 60
+	if hours == 0 {
+		return fmt.Sprintf("%dm%02ds", minutes, seconds)
+	}
+	return fmt.Sprintf("%dh%02dm%02ds", hours, minutes, seconds)
+}
+
+// Helper function to render a limit's utilization over the window before now
+// as width cells. History only records changes, so each cell shows the
+// reading in effect at its end; cells before the first reading are blank.
+func renderSparkline(entries []historyEntry, get func(*claude.UsageLimits) *claude.UsageLimit, now time.Time, window time.Duration, width int) string {
+	from := now.Add(-window)
+	cells := make([]rune, width)
+	next := 0
+	var current *claude.UsageLimit
+	for i := range cells {
+		end := from.Add(window * time.Duration(i+1) / time.Duration(width))
+		for next < len(entries) && !entries[next].Timestamp.After(end) {
+			current = get(entries[next].Limits)
+			next++
+		}
+
+		if current == nil {
+			cells[i] = ' '
+			continue
+		}
+		level := int(current.Utilization/100*float64(len(sparklineBlocks)-1) + 0.5)
+		cells[i] = sparklineBlocks[max(0, min(level, len(sparklineBlocks)-1))]
+	}
+	return string(cells)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+func TestRenderSparkline(t *testing.T) {
+	now := time.Date(2025, 6, 12, 14, 0, 0, 0, time.UTC)
+	fiveHour := func(l *claude.UsageLimits) *claude.UsageLimit { return l.FiveHour }
+	entry := func(ago time.Duration, utilization float64) historyEntry {
+		return historyEntry{
+			Timestamp: now.Add(-ago),
+			Limits:    &claude.UsageLimits{FiveHour: &claude.UsageLimit{Utilization: utilization}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		entries []historyEntry
+		want    string
+	}{
+		{"no history", nil, "     "},
+		{"reading holds until the next", []historyEntry{entry(5*time.Hour, 0), entry(2*time.Hour, 100)}, "▁▁███"},
+		{"blank before the first reading", []historyEntry{entry(150*time.Minute, 50)}, "  ▅▅▅"},
+		{"limit missing from entries", []historyEntry{{Timestamp: now.Add(-time.Hour), Limits: &claude.UsageLimits{}}}, "     "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderSparkline(tt.entries, fiveHour, now, 5*time.Hour, 5); got != tt.want {
+				t.Errorf("renderSparkline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderGauge(t *testing.T) {
+	tests := []struct {
+		percent float64
+		want    string
+	}{
+		{0, "░░░░░░░░░░"},
+		{42, "████░░░░░░"},
+		{45, "█████░░░░░"},
+		{100, "██████████"},
+		{120, "██████████"},
+	}
+
+	for _, tt := range tests {
+		if got := renderGauge(tt.percent, 10); got != tt.want {
+			t.Errorf("renderGauge(%v) = %q, want %q", tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCountdown(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{2*time.Hour + 5*time.Minute + 9*time.Second + 500*time.Millisecond, "2h05m09s"},
+		{59 * time.Second, "0m59s"},
+		{150 * time.Hour, "150h00m00s"},
+		{-time.Minute, "0m00s"},
+	}
+
+	for _, tt := range tests {
+		if got := formatCountdown(tt.d); got != tt.want {
+			t.Errorf("formatCountdown(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// enableTerminalEscapes is a no-op: Unix terminals handle ANSI escapes
+func enableTerminalEscapes() {}
+
+// watchResize signals on the returned channel whenever the terminal is
+// resized, until ctx is done
+func watchResize(ctx context.Context) <-chan struct{} {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+	resized := make(chan struct{}, 1)
+	go func() {
+		defer signal.Stop(sigs)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigs:
+				select {
+				case resized <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return resized
+}
+
+// terminalSize returns the terminal's columns and rows, asking stty so no
+// ioctl plumbing is needed per platform
+func terminalSize() (width, height int) {
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return defaultTerminalWidth, defaultTerminalHeight
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return defaultTerminalWidth, defaultTerminalHeight
+	}
+	rows, rowsErr := strconv.Atoi(fields[0])
+	cols, colsErr := strconv.Atoi(fields[1])
+	if rowsErr != nil || colsErr != nil || rows <= 0 || cols <= 0 {
+		return defaultTerminalWidth, defaultTerminalHeight
+	}
+	return cols, rows
+}
@@ -0,0 +1,83 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	// Console mode flag that makes the console interpret ANSI escapes
+	enableVirtualTerminalProcessing = 0x0004
+
+	resizePollInterval = 500 * time.Millisecond
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+// consoleScreenBufferInfo mirrors CONSOLE_SCREEN_BUFFER_INFO
+type consoleScreenBufferInfo struct {
+	size              [2]int16
+	cursorPosition    [2]int16
+	attributes        uint16
+	window            [4]int16 // left, top, right, bottom
+	maximumWindowSize [2]int16
+}
+
+// enableTerminalEscapes turns on ANSI escape handling, which older consoles
+// leave off. Best effort: on failure the escapes show as text.
+func enableTerminalEscapes() {
+	handle := syscall.Handle(os.Stdout.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return
+	}
+	procSetConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing))
+}
+
+// watchResize signals on the returned channel whenever the console window
+// is resized, until ctx is done. Windows has no resize signal, so the size
+// is polled.
+func watchResize(ctx context.Context) <-chan struct{} {
+	resized := make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(resizePollInterval)
+		defer ticker.Stop()
+		width, height := terminalSize()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w, h := terminalSize()
+				if w == width && h == height {
+					continue
+				}
+				width, height = w, h
+				select {
+				case resized <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return resized
+}
+
+// terminalSize returns the console window's columns and rows
+func terminalSize() (width, height int) {
+	var info consoleScreenBufferInfo
+	ok, _, _ := procGetConsoleScreenBufferInfo.Call(os.Stdout.Fd(), uintptr(unsafe.Pointer(&info)))
+	if ok == 0 {
+		return defaultTerminalWidth, defaultTerminalHeight
+	}
+	return int(info.window[2]-info.window[0]) + 1, int(info.window[3]-info.window[1]) + 1
+}
@@ -0,0 +1,208 @@
+// diagnose.go - Self-checks for when the menu bar doesn't appear
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+const (
+	defaultDiagnoseLogLines = 100
+	redactedValue           = "[redacted]"
+)
+
+// Config keys left out of a diagnostics bundle. The commands often hold
+// tokens (a webhook URL) or vault paths.
+var redactedConfigKeys = []string{"accountName", "accountEmail", "criticalHookCommand", "sessionKeyCommand"}
+
+func handleDiagnose(args []string) {
+	fs := flag.NewFlagSet("diagnose", flag.ExitOnError)
+	output := fs.String("output", "", "Write a bundle with the report, config and recent log to `FILE`")
+	lines := fs.Int("lines", defaultDiagnoseLogLines, "Log lines to include in the bundle")
+	fs.Parse(args)
+
+	if *output == "" {
+		writeDiagnostics(os.Stdout)
+		return
+	}
+
+	f, err := os.OpenFile(*output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, configFilePermissions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create bundle: %v\n", err)
+		os.Exit(exitError)
+	}
+	defer f.Close()
+
+	writeDiagnostics(f)
+	writeBundleSections(f, *lines)
+	infof("✓ Diagnostics written to %s. Review it before attaching it to a bug report.\n", *output)
+}
+
+// writeDiagnostics writes the self-check report
+func writeDiagnostics(w io.Writer) {
+	fmt.Fprintln(w, "=== Claude Monitor Lite Diagnostics ===")
+	fmt.Fprintf(w, "Version:      %s\n", version)
+	fmt.Fprintf(w, "Platform:     %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(w, "Config:       %s\n", GetConfigPath())
+	fmt.Fprintf(w, "Log file:     %s\n", logFile)
+
+	if _, err := LoadAuthSession(); err != nil {
+		fmt.Fprintln(w, "Session:      not logged in")
+	} else {
+		fmt.Fprintln(w, "Session:      present")
+	}
+
+	if isRunning() {
+		fmt.Fprintln(w, "Daemon:       running")
+	} else {
+		fmt.Fprintln(w, "Daemon:       not running")
+	}
+
+	fmt.Fprintf(w, "TLS:          %s\n", describeTLS())
+	fmt.Fprintf(w, "Display:      %s\n", describeDisplay())
+
+	if status := lastLogLine(systrayReadyMessage, systrayNotReadyMessage); status != "" {
+		fmt.Fprintf(w, "Menu bar:     %s\n", status)
+	} else {
+		fmt.Fprintln(w, "Menu bar:     unknown (no startup recorded in log)")
+	}
+
+	if status := lastLogLine(apiOKMessage, apiFailedMessage); status != "" {
+		fmt.Fprintf(w, "Last fetch:   %s\n", status)
+	} else {
+		fmt.Fprintln(w, "Last fetch:   unknown (none recorded in log)")
+	}
+}
+
+// describeTLS reports which certificates requests are verified against, and
+// whether the configured files load
+func describeTLS() string {
+	config := currentConfig()
+	if config.CAFile == "" && config.ClientCertFile == "" && config.ClientKeyFile == "" {
+		return "system CAs"
+	}
+	if _, err := claude.LoadTLSConfig(config.CAFile, config.ClientCertFile, config.ClientKeyFile); err != nil {
+		return "error: " + err.Error()
+	}
+
+	var parts []string
+	if config.CAFile != "" {
+		parts = append(parts, "system CAs + "+config.CAFile)
+	}
+	if config.ClientCertFile != "" {
+		parts = append(parts, "client certificate "+config.ClientCertFile)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeBundleSections appends the redacted config and the tail of the log
+func writeBundleSections(w io.Writer, logLines int) {
+	fmt.Fprintf(w, "\n=== Config (generated %s) ===\n", time.Now().Format(time.RFC3339))
+	if data, err := os.ReadFile(GetConfigPath()); err != nil {
+		fmt.Fprintf(w, "unavailable: %v\n", err)
+	} else {
+		fmt.Fprintln(w, redactConfig(data))
+	}
+
+	fmt.Fprintf(w, "\n=== Last %d log lines ===\n", logLines)
+	f, err := os.Open(logFile)
+	if err != nil {
+		fmt.Fprintf(w, "unavailable: %v\n", err)
+		return
+	}
+	defer f.Close()
+	for _, line := range tailLines(f, logLines) {
+		// Lines from before log redaction may still hold a key
+		fmt.Fprintln(w, claude.RedactSecrets(line))
+	}
+}
+
+// redactConfig returns the config JSON with the session key masked and the
+// account details replaced, keeping every other key for context
+func redactConfig(data []byte) string {
+	var config map[string]any
+	if err := json.Unmarshal(data, &config); err != nil {
+		// Can't tell the secrets apart in a corrupt file, so leave it all out
+		return fmt.Sprintf("not valid JSON (%v); contents omitted", err)
+	}
+
+	for _, key := range redactedConfigKeys {
+		if value, ok := config[key]; ok && value != "" {
+			config[key] = redactedValue
+		}
+	}
+	if key, ok := config["sessionKey"].(string); ok && key != "" {
+		// Enough to tell which key was in use, but not to use it
+		config["sessionKey"] = claude.RedactSessionKey(key)
+	}
+
+	out, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("unavailable: %v", err)
+	}
+	return string(out)
+}
+
+// tailLines returns the last n lines read from r
+func tailLines(r io.Reader, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, n)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if len(lines) == n {
+			lines = lines[1:]
+		}
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// describeDisplay reports whether a system tray is likely to be available
+func describeDisplay() string {
+	switch runtime.GOOS {
+	case "linux":
+		if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			return "none (DISPLAY and WAYLAND_DISPLAY unset; the menu bar can't appear)"
+		}
+		return "available (requires an AppIndicator-compatible tray)"
+	case "darwin", "windows":
+		return "available"
+	default:
+		return "unknown"
+	}
+}
+
+// lastLogLine returns the most recent log line containing any of markers
+func lastLogLine(markers ...string) string {
+	f, err := os.Open(logFile)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var status string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for _, marker := range markers {
+			if strings.Contains(line, marker) {
+				status = line
+				break
+			}
+		}
+	}
+	return status
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+func TestRedactConfig(t *testing.T) {
+	got := redactConfig([]byte(`{"sessionKey": "sk-ant-secret", "accountEmail": "a@example.com", "organizationId": "org-1", "accountName": ""}`))
+
+	for _, secret := range []string{"sk-ant-secret", "a@example.com"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("redactConfig() leaked %q:\n%s", secret, got)
+		}
+	}
+	if !strings.Contains(got, `"organizationId": "org-1"`) {
+		t.Errorf("redactConfig() dropped organizationId:\n%s", got)
+	}
+	if strings.Count(got, redactedValue) != 2 {
+		t.Errorf("redactConfig() should only mark set values as redacted:\n%s", got)
+	}
+
+	got = redactConfig([]byte(`{"sessionKeyCommand": "op read op://Private/claude/key", "criticalHookCommand": "curl https://hooks.example.com/T0K3N"}`))
+	for _, secret := range []string{"op://Private", "T0K3N"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("redactConfig() leaked %q:\n%s", secret, got)
+		}
+	}
+
+	key := "sk-ant-REDACTED"
+	got = redactConfig([]byte(`{"sessionKey": "` + key + `"}`))
+	if strings.Contains(got, key) || !strings.Contains(got, claude.RedactSessionKey(key)) {
+		t.Errorf("redactConfig() should mask a full-length key:\n%s", got)
+	}
+
+	if got := redactConfig([]byte(`{"sessionKey": "sk-ant-sec`)); strings.Contains(got, "sk-ant") {
+		t.Errorf("redactConfig() on corrupt JSON leaked contents: %s", got)
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	tests := []struct {
+		input string
+		n     int
+		want  string
+	}{
+		{"a\nb\nc\nd\n", 2, "c|d"},
+		{"a\nb\n", 5, "a|b"},
+		{"a\nb\n", 0, ""},
+		{"", 3, ""},
+	}
+
+	for _, tt := range tests {
+		if got := strings.Join(tailLines(strings.NewReader(tt.input), tt.n), "|"); got != tt.want {
+			t.Errorf("tailLines(%q, %d) = %q, want %q", tt.input, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// events.go - Fan-out of each successful fetch to the parts that react to it
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+// UsageUpdated is published after every successful fetch
+type UsageUpdated struct {
+	Previous *claude.UsageLimits // nil on the first fetch
+	Limits   *claude.UsageLimits
+	Changed  bool // Any utilization or reset time differs from Previous
+	At       time.Time
+}
+
+// usageHandler reacts to a fetch. Handlers run on the fetch goroutine, in
+// the order they subscribed, so they should return quickly.
+type usageHandler func(UsageUpdated)
+
+// eventBus delivers UsageUpdated events to its subscribers
+type eventBus struct {
+	mu       sync.RWMutex
+	handlers []usageHandler
+}
+
+// Published to by fetchStats in the running monitor
+var usageEvents = &eventBus{}
+
+// subscribe adds handler for every later event
+func (b *eventBus) subscribe(handler usageHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// publish calls each handler with event
+func (b *eventBus) publish(event UsageUpdated) {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// subscribeUsageHandlers wires up the handlers, shared with headless mode.
+// The burn tracker and history go first since the menu and notifications
+// read them, then the menu redraw, so the side effects after it never hold
+// up the display.
+func subscribeUsageHandlers() {
+	usageEvents.subscribe(trackBurnRate)
+	usageEvents.subscribe(recordHistory)
+	if !headlessMode {
+		usageEvents.subscribe(redrawUsage)
+	}
+	usageEvents.subscribe(refetchIfStale)
+	usageEvents.subscribe(streamToFifo)
+	usageEvents.subscribe(notifyUsage)
+	usageEvents.subscribe(runCriticalHook)
+}
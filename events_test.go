@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+func TestEventBusPublish(t *testing.T) {
+	bus := &eventBus{}
+	event := UsageUpdated{
+		Limits:  &claude.UsageLimits{FiveHour: &claude.UsageLimit{Utilization: 42}},
+		Changed: true,
+		At:      time.Now(),
+	}
+
+	// Nothing subscribed yet
+	bus.publish(event)
+
+	var calls []string
+	bus.subscribe(func(e UsageUpdated) {
+		calls = append(calls, "menu")
+		if e.Limits.FiveHour.Utilization != 42 || !e.Changed {
+			t.Errorf("menu handler got %+v, want the published event", e)
+		}
+	})
+	bus.subscribe(func(UsageUpdated) { calls = append(calls, "history") })
+	bus.subscribe(func(UsageUpdated) { calls = append(calls, "notify") })
+
+	bus.publish(event)
+	bus.publish(event)
+
+	want := []string{"menu", "history", "notify", "menu", "history", "notify"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("handler calls = %v, want %v", calls, want)
+	}
+}
+
+func TestRecordHistoryOnlyWhenChanged(t *testing.T) {
+	oldHistory := historyFile
+	defer func() { historyFile = oldHistory }()
+	historyFile = filepath.Join(t.TempDir(), "history.jsonl")
+	setTestConfig(t, func(c *Config) { c.RecordHistory = true })
+
+	bus := &eventBus{}
+	bus.subscribe(recordHistory)
+
+	limits := &claude.UsageLimits{FiveHour: &claude.UsageLimit{Utilization: 10}, LastUpdated: time.Now()}
+	bus.publish(UsageUpdated{Limits: limits, Changed: true, At: time.Now()})
+	bus.publish(UsageUpdated{Previous: limits, Limits: limits, Changed: false, At: time.Now()})
+
+	entries, err := readHistory(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("history has %d entries, want 1", len(entries))
+	}
+}
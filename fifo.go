@@ -0,0 +1,124 @@
+// fifo.go - Named pipe streaming each fresh reading as a JSON line
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+const (
+	fifoFilePermissions = 0600 // Owner read/write only
+)
+
+var (
+	fifoMutex   sync.Mutex
+	fifoEnabled bool
+	fifoWriter  *os.File // Open while a reader is attached
+	fifoPending []byte   // Rest of a line the pipe only took part of
+)
+
+// startFifo creates the pipe that publishFifo writes to.
+// Stream with: cat <data dir>/monitor.fifo
+func startFifo() {
+	// Remove a stale pipe left behind by a crashed daemon
+	if err := os.Remove(fifoFile); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: Failed to remove stale FIFO: %v\n", err)
+		return
+	}
+	if err := makeFifo(fifoFile, fifoFilePermissions); err != nil {
+		log.Printf("Warning: Failed to create FIFO: %v\n", err)
+		return
+	}
+
+	fifoMutex.Lock()
+	fifoEnabled = true
+	fifoMutex.Unlock()
+}
+
+// publishFifo writes limits to the pipe as one JSON line. It never blocks:
+// with no reader, or a reader too slow to keep up, the line is dropped.
+func publishFifo(limits *claude.UsageLimits) {
+	fifoMutex.Lock()
+	defer fifoMutex.Unlock()
+
+	if !fifoEnabled {
+		return
+	}
+	if fifoWriter == nil {
+		f, err := openFifoWriter(fifoFile)
+		if err != nil {
+			// ENXIO: nobody is reading
+			return
+		}
+		fifoWriter = f
+	}
+
+	line, err := json.Marshal(socketResponse{UsageLimits: limits, LastUpdated: limits.LastUpdated})
+	if err != nil {
+		log.Printf("Warning: Failed to encode usage for FIFO: %v\n", err)
+		return
+	}
+
+	if len(fifoPending) > 0 {
+		// Finish the line a full pipe cut short first, so the reader never
+		// sees half a line run into the next. Still full: drop this line.
+		if !flushFifo() || len(fifoPending) > 0 {
+			return
+		}
+	}
+	fifoPending = append(line, '\n')
+	if flushFifo() && len(fifoPending) == len(line)+1 {
+		// Pipe full and nothing written: drop this line, keep the reader
+		fifoPending = nil
+	}
+}
+
+// flushFifo writes what it can of fifoPending, keeping the rest for later.
+// PIPE_BUF is only 512 bytes on macOS, so a line can be written in part.
+// Returns false if the reader went away. The caller holds fifoMutex.
+func flushFifo() bool {
+	n, err := writeFifo(fifoWriter, fifoPending)
+	fifoPending = fifoPending[n:]
+	if err == nil || errors.Is(err, syscall.EAGAIN) {
+		return true
+	}
+	if !errors.Is(err, syscall.EPIPE) {
+		log.Printf("Warning: Failed to write to FIFO: %v\n", err)
+	}
+	// The reader went away; reopen when the next one arrives
+	fifoWriter.Close()
+	fifoWriter = nil
+	fifoPending = nil
+	return false
+}
+
+// streamToFifo publishes each fetch to the pipe
+func streamToFifo(event UsageUpdated) {
+	publishFifo(event.Limits)
+}
+
+// stopFifo closes the pipe and removes it
+func stopFifo() {
+	fifoMutex.Lock()
+	defer fifoMutex.Unlock()
+
+	if !fifoEnabled {
+		return
+	}
+	fifoEnabled = false
+	if fifoWriter != nil {
+		fifoWriter.Close()
+		fifoWriter = nil
+	}
+	fifoPending = nil
+	if err := os.Remove(fifoFile); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: Failed to remove FIFO: %v\n", err)
+	}
+}
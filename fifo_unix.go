@@ -0,0 +1,39 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// makeFifo creates a named pipe at path
+func makeFifo(path string, perm uint32) error {
+	return syscall.Mkfifo(path, perm)
+}
+
+// openFifoWriter opens the pipe for writing without waiting for a reader.
+// It fails with ENXIO if there is none.
+func openFifoWriter(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+}
+
+// writeFifo makes a single write attempt, returning EAGAIN rather than
+// waiting when the pipe is full. A nearly full pipe may take only part of
+// data; the count says how much was written.
+func writeFifo(f *os.File, data []byte) (int, error) {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	var writeErr error
+	err = conn.Write(func(fd uintptr) bool {
+		n, writeErr = syscall.Write(int(fd), data)
+		return true // Don't wait for the pipe to drain
+	})
+	if err != nil {
+		return 0, err
+	}
+	return max(n, 0), writeErr
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// Windows named pipes live in their own namespace, not the filesystem
+var errFifoUnsupported = errors.New("FIFOs are not supported on Windows")
+
+func makeFifo(path string, perm uint32) error {
+	return errFifoUnsupported
+}
+
+func openFifoWriter(path string) (*os.File, error) {
+	return nil, errFifoUnsupported
+}
+
+func writeFifo(f *os.File, data []byte) (int, error) {
+	return 0, errFifoUnsupported
+}
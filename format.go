@@ -0,0 +1,88 @@
+// format.go - Output formats for the fetch and status commands (--format)
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+// Named values for --format; anything else is a Go template
+const (
+	formatText = "text"
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+// Functions available to --format templates
+var usageTemplateFuncs = template.FuncMap{
+	// {{percent .FiveHour}} prints "32%", or "--" for a missing limit
+	"percent": func(limit *claude.UsageLimit) string {
+		if limit == nil {
+			return "--"
+		}
+		return formatPercent(limit.Utilization)
+	},
+}
+
+// parseUsageTemplate parses a --format value that isn't one of the named
+// formats as a Go template over claude.UsageLimits
+func parseUsageTemplate(spec string) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(usageTemplateFuncs).Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --format template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// checkUsageFormat reports a bad --format value before anything is fetched
+func checkUsageFormat(spec string) error {
+	switch spec {
+	case formatText, formatJSON, formatCSV:
+		return nil
+	}
+	if !strings.Contains(spec, "{{") {
+		return fmt.Errorf("unknown --format %q (want text, json, csv or a Go template like '{{.FiveHour.Utilization}}')", spec)
+	}
+	_, err := parseUsageTemplate(spec)
+	return err
+}
+
+// formatUsage writes limits in the given --format: the human-readable
+// summary, the JSON document served on the socket, a CSV row with the
+// export command's columns, or a Go template followed by a newline
+func formatUsage(w io.Writer, limits *claude.UsageLimits, spec string) error {
+	if err := checkUsageFormat(spec); err != nil {
+		return err
+	}
+
+	switch spec {
+	case formatText:
+		writeUsageStats(w, limits)
+		return nil
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(socketResponse{UsageLimits: limits, LastUpdated: limits.LastUpdated})
+	case formatCSV:
+		return writeHistoryCSV(w, []historyEntry{{Timestamp: limits.LastUpdated, Limits: limits}})
+	}
+
+	tmpl, err := parseUsageTemplate(spec)
+	if err != nil {
+		return err
+	}
+	// Buffered so a failing template prints nothing rather than half a line
+	var b strings.Builder
+	if err := tmpl.Execute(&b, limits); err != nil {
+		return fmt.Errorf("--format template failed: %w", err)
+	}
+	b.WriteString("\n")
+	_, err = io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+func TestFormatUsage(t *testing.T) {
+	setTestConfig(t, func(c *Config) { c.UseEmoji = false })
+
+	limits := &claude.UsageLimits{
+		FiveHour:    &claude.UsageLimit{Utilization: 32, ResetsAt: "2025-06-01T12:00:00Z"},
+		SevenDay:    &claude.UsageLimit{Utilization: 55.5},
+		LastUpdated: time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name:   "json",
+			format: "json",
+			want: `{
+  "five_hour": {
+    "utilization": 32,
+    "resets_at": "2025-06-01T12:00:00Z"
+  },
+  "seven_day": {
+    "utilization": 55.5,
+    "resets_at": ""
+  },
+  "last_updated": "2025-06-01T10:00:00Z"
+}
+`,
+		},
+		{
+			name:   "csv",
+			format: "csv",
+			want:   "timestamp,five_hour_util,seven_day_util,opus_util\r\n2025-06-01T10:00:00Z,32,55.5,\r\n",
+		},
+		{
+			name:   "template",
+			format: "5h={{.FiveHour.Utilization}}",
+			want:   "5h=32\n",
+		},
+		{
+			name:   "template with percent",
+			format: "{{percent .FiveHour}} {{percent .SevenDayOpus}}",
+			want:   "32% --\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := formatUsage(&b, limits, tt.format); err != nil {
+				t.Fatalf("formatUsage() error = %v", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("formatUsage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("text", func(t *testing.T) {
+		var b strings.Builder
+		if err := formatUsage(&b, limits, "text"); err != nil {
+			t.Fatalf("formatUsage() error = %v", err)
+		}
+		if got := b.String(); !strings.HasPrefix(got, "=== Current Usage ===\n") || !strings.Contains(got, "5-Hour Session:") {
+			t.Errorf("formatUsage() text = %q, want the usage summary", got)
+		}
+	})
+}
+
+func TestFormatUsageErrors(t *testing.T) {
+	limits := &claude.UsageLimits{FiveHour: &claude.UsageLimit{Utilization: 32}}
+
+	tests := []struct {
+		name    string
+		format  string
+		wantErr string
+	}{
+		{"unknown name", "yaml", `unknown --format "yaml"`},
+		{"unclosed action", "{{.FiveHour.Utilization", "invalid --format template"},
+		{"unknown function", "{{bogus .FiveHour}}", "invalid --format template"},
+		{"unknown field", "{{.Hourly}}", "--format template failed"},
+		{"missing limit", "{{.SevenDay.Utilization}}", "--format template failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			err := formatUsage(&b, limits, tt.format)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("formatUsage(%q) error = %v, want one containing %q", tt.format, err, tt.wantErr)
+			}
+			if b.Len() != 0 {
+				t.Errorf("formatUsage(%q) wrote %q before failing", tt.format, b.String())
+			}
+		})
+	}
+
+	// Parse errors are caught before fetching; execution errors can't be
+	if err := checkUsageFormat("{{.Hourly}}"); err != nil {
+		t.Errorf("checkUsageFormat() error = %v, want nil until it runs", err)
+	}
+	if err := checkUsageFormat("{{end}}"); err == nil {
+		t.Error("checkUsageFormat(\"{{end}}\") = nil, want a parse error")
+	}
+}
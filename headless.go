@@ -0,0 +1,53 @@
+// headless.go - Polling without the menu bar, for machines with no display
+
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// runHeadless runs the refresh loop directly instead of via systray.Run, for
+// the socket and history without a menu bar. Blocks until shutdown.
+func runHeadless() {
+	session, err := LoadAuthSession()
+	if err != nil {
+		log.Println("ERROR: Not authenticated. Run 'claude-monitor-lite login' first.")
+		cleanup()
+		os.Exit(exitNotAuthenticated)
+	}
+	claudeClient.Store(createClientFromSession(session))
+
+	config := currentConfig()
+	applyPaused(config.Paused)
+	applySnooze(config.SnoozeUntil)
+	fetchWG.Go(warmUpStats)
+
+	if config.EnableSocket {
+		startSocketServer()
+	}
+	if config.EnableFifo {
+		startFifo()
+	}
+	log.Println("Running headless")
+
+	ticker := time.NewTicker(nextRefreshInterval())
+	defer ticker.Stop()
+	woke := watchForWake(appCtx)
+
+	for {
+		select {
+		case <-appCtx.Done():
+			return
+		case <-ticker.C:
+			startUpdate()
+			ticker.Reset(nextRefreshInterval())
+		case <-woke:
+			handleWake()
+			ticker.Reset(nextRefreshInterval())
+		case <-reloadChan:
+			reloadConfig()
+		}
+	}
+}
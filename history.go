@@ -0,0 +1,310 @@
+// history.go - Local usage history (JSONL) and CSV export
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+const (
+	historyFilePermissions = 0600 // Owner read/write only
+
+	// Period covered by the Recent menu
+	recentHistoryWindow = 5 * time.Hour
+
+	// Entries older than this are pruned on startup
+	historyRetention = 90 * 24 * time.Hour
+)
+
+var (
+	historyMutex sync.Mutex
+
+	// The Recent menu's entries, kept in memory so each redraw doesn't
+	// re-read the file. Loaded on first use; guarded by historyMutex.
+	recentEntries []historyEntry
+	recentLoaded  bool
+)
+
+// historyEntry is one line of the history file
+type historyEntry struct {
+	Timestamp time.Time           `json:"timestamp"`
+	Limits    *claude.UsageLimits `json:"limits"`
+}
+
+// recordHistory appends fetches that changed usage when recordHistory is
+// set. An unchanged fetch adds nothing the previous entry doesn't already say.
+func recordHistory(event UsageUpdated) {
+	if currentConfig().RecordHistory && event.Changed {
+		appendHistory(event.Limits)
+	}
+}
+
+// appendHistory records a successful fetch as one JSON line
+func appendHistory(limits *claude.UsageLimits) {
+	line, err := json.Marshal(historyEntry{Timestamp: limits.LastUpdated, Limits: limits})
+	if err != nil {
+		log.Printf("Warning: Failed to encode history entry: %v\n", err)
+		return
+	}
+
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	f, err := os.OpenFile(historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, historyFilePermissions)
+	if err != nil {
+		log.Printf("Warning: Failed to open history file: %v\n", err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Printf("Warning: Failed to write history: %v\n", err)
+		return
+	}
+	if recentLoaded {
+		recentEntries = append(recentEntries, historyEntry{Timestamp: limits.LastUpdated, Limits: limits})
+	}
+}
+
+// recentHistory returns the entries for the recentHistoryWindow before now,
+// see historyWindow. The file is read once; later entries come from
+// appendHistory.
+func recentHistory(now time.Time) []historyEntry {
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	since := now.Add(-recentHistoryWindow)
+	if !recentLoaded {
+		// Missing when nothing has been recorded yet
+		recentEntries, _ = readHistory(time.Time{})
+		recentLoaded = true
+	}
+	recentEntries = historyWindow(recentEntries, since)
+	return slices.Clone(recentEntries)
+}
+
+// historyWindow cuts entries, in time order, down to those after since. Only
+// changes are recorded, so the reading in effect at since leads, moved up to
+// since; a window with no changes still has the current reading.
+func historyWindow(entries []historyEntry, since time.Time) []historyEntry {
+	start := 0
+	for start+1 < len(entries) && !entries[start+1].Timestamp.After(since) {
+		start++
+	}
+	window := slices.Clone(entries[start:])
+	if len(window) > 0 && window[0].Timestamp.Before(since) {
+		window[0].Timestamp = since
+	}
+	return window
+}
+
+// pruneHistory drops entries older than historyRetention, so the file
+// doesn't grow forever. Run on startup, like the log's rotation.
+func pruneHistory(now time.Time) {
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	data, err := os.ReadFile(historyFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: Failed to read history: %v\n", err)
+		}
+		return
+	}
+
+	// Entries are appended in time order, so everything from the first one
+	// inside the retention period on is kept
+	cutoff := now.Add(-historyRetention)
+	start := 0
+	for start < len(data) {
+		line, _, _ := bytes.Cut(data[start:], []byte("\n"))
+		var entry historyEntry
+		if json.Unmarshal(line, &entry) == nil && !entry.Timestamp.Before(cutoff) {
+			break
+		}
+		start += len(line) + 1
+	}
+	if start == 0 {
+		return
+	}
+
+	err = writeFileAtomic(historyFile, historyFilePermissions, func(w io.Writer) error {
+		_, err := w.Write(data[min(start, len(data)):])
+		return err
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to prune history: %v\n", err)
+	}
+}
+
+// readHistory returns history entries recorded at or after since.
+// Malformed lines (e.g. from an interrupted write) are skipped.
+func readHistory(since time.Time) ([]historyEntry, error) {
+	f, err := os.Open(historyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Limits == nil {
+			continue
+		}
+		if entry.Timestamp.Before(since) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// usageSummary is the range of one limit's utilization over a period
+type usageSummary struct {
+	Min, Max, Avg float64
+}
+
+// summarizeHistory summarizes one limit across entries, which are in time
+// order. Entries are only written when usage changes, so each reading counts
+// until the next one (or now) in the average. Returns false if no entry has
+// the limit.
+func summarizeHistory(entries []historyEntry, get func(*claude.UsageLimits) *claude.UsageLimit, now time.Time) (usageSummary, bool) {
+	var summary usageSummary
+	var weighted, total float64
+	found := false
+	for i, entry := range entries {
+		limit := get(entry.Limits)
+		if limit == nil {
+			continue
+		}
+
+		if !found {
+			summary.Min, summary.Max = limit.Utilization, limit.Utilization
+			found = true
+		}
+		summary.Min = min(summary.Min, limit.Utilization)
+		summary.Max = max(summary.Max, limit.Utilization)
+
+		until := now
+		if i+1 < len(entries) {
+			until = entries[i+1].Timestamp
+		}
+		if span := until.Sub(entry.Timestamp).Seconds(); span > 0 {
+			weighted += limit.Utilization * span
+			total += span
+		}
+	}
+	if !found {
+		return usageSummary{}, false
+	}
+
+	if total > 0 {
+		summary.Avg = weighted / total
+	} else {
+		summary.Avg = (summary.Min + summary.Max) / 2
+	}
+	return summary, true
+}
+
+// writeHistoryCSV writes entries as RFC 4180 CSV. Missing limits become empty cells.
+func writeHistoryCSV(w io.Writer, entries []historyEntry) error {
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+
+	if err := cw.Write([]string{"timestamp", "five_hour_util", "seven_day_util", "opus_util"}); err != nil {
+		return err
+	}
+
+	formatUtil := func(limit *claude.UsageLimit) string {
+		if limit == nil {
+			return ""
+		}
+		return strconv.FormatFloat(limit.Utilization, 'f', -1, 64)
+	}
+
+	for _, entry := range entries {
+		record := []string{
+			entry.Timestamp.Format(time.RFC3339),
+			formatUtil(entry.Limits.FiveHour),
+			formatUtil(entry.Limits.SevenDay),
+			formatUtil(entry.Limits.SevenDayOpus),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// parseSinceDate accepts a local date (2006-01-02) or an RFC3339 timestamp
+func parseSinceDate(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func handleExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	since := fs.String("since", "", "Only export entries on or after this date (YYYY-MM-DD)")
+	output := fs.String("output", "", "Write CSV to this file instead of stdout")
+	fs.Parse(args)
+
+	var sinceTime time.Time
+	if *since != "" {
+		t, err := parseSinceDate(*since)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --since date %q (expected YYYY-MM-DD)\n", *since)
+			os.Exit(exitError)
+		}
+		sinceTime = t
+	}
+
+	entries, err := readHistory(sinceTime)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Fprintln(os.Stderr, "No history recorded yet. Set \"recordHistory\": true in the config to start.")
+		} else {
+			fmt.Fprintf(os.Stderr, "Failed to read history: %v\n", err)
+		}
+		os.Exit(exitError)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create output file: %v\n", err)
+			os.Exit(exitError)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := writeHistoryCSV(w, entries); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write CSV: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	if *output != "" {
+		fmt.Printf("✓ Exported %d entries to %s\n", len(entries), *output)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+func TestWriteHistoryCSV(t *testing.T) {
+	entries := []historyEntry{
+		{
+			Timestamp: time.Date(2025, 6, 12, 14, 30, 0, 0, time.UTC),
+			Limits: &claude.UsageLimits{
+				FiveHour:     &claude.UsageLimit{Utilization: 42.5},
+				SevenDay:     &claude.UsageLimit{Utilization: 10},
+				SevenDayOpus: nil,
+			},
+		},
+	}
+
+	var sb strings.Builder
+	if err := writeHistoryCSV(&sb, entries); err != nil {
+		t.Fatalf("writeHistoryCSV() error = %v", err)
+	}
+
+	want := "timestamp,five_hour_util,seven_day_util,opus_util\r\n" +
+		"2025-06-12T14:30:00Z,42.5,10,\r\n"
+	if got := sb.String(); got != want {
+		t.Errorf("writeHistoryCSV() = %q, want %q", got, want)
+	}
+}
+
+func TestHistoryRoundTrip(t *testing.T) {
+	historyFile = filepath.Join(t.TempDir(), "history.jsonl")
+
+	old := time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+	recent := time.Date(2025, 6, 12, 12, 0, 0, 0, time.Local)
+	appendHistory(&claude.UsageLimits{FiveHour: &claude.UsageLimit{Utilization: 5}, LastUpdated: old})
+	appendHistory(&claude.UsageLimits{FiveHour: &claude.UsageLimit{Utilization: 50}, LastUpdated: recent})
+
+	since, err := parseSinceDate("2025-06-10")
+	if err != nil {
+		t.Fatalf("parseSinceDate() error = %v", err)
+	}
+
+	entries, err := readHistory(since)
+	if err != nil {
+		t.Fatalf("readHistory() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Limits.FiveHour.Utilization != 50 {
+		t.Errorf("readHistory() = %+v, want only the recent entry", entries)
+	}
+}
+
+func TestPruneHistory(t *testing.T) {
+	historyFile = filepath.Join(t.TempDir(), "history.jsonl")
+
+	now := time.Date(2025, 6, 12, 12, 0, 0, 0, time.Local)
+	for _, at := range []time.Time{now.Add(-historyRetention - time.Hour), now.Add(-historyRetention + time.Hour), now} {
+		appendHistory(&claude.UsageLimits{FiveHour: &claude.UsageLimit{Utilization: 5}, LastUpdated: at})
+	}
+
+	pruneHistory(now)
+	entries, err := readHistory(time.Time{})
+	if err != nil {
+		t.Fatalf("readHistory() error = %v", err)
+	}
+	if len(entries) != 2 || !entries[1].Timestamp.Equal(now) {
+		t.Errorf("after pruning, history = %+v, want the 2 entries within the retention period", entries)
+	}
+
+	// Nothing old left: the file is left as it is
+	pruneHistory(now)
+	if entries, _ := readHistory(time.Time{}); len(entries) != 2 {
+		t.Errorf("pruning again left %d entries, want 2", len(entries))
+	}
+}
+
+func TestRecentHistory(t *testing.T) {
+	historyFile = filepath.Join(t.TempDir(), "history.jsonl")
+	recentEntries, recentLoaded = nil, false
+	t.Cleanup(func() { recentEntries, recentLoaded = nil, false })
+
+	now := time.Date(2025, 6, 12, 12, 0, 0, 0, time.Local)
+	appendHistory(&claude.UsageLimits{FiveHour: &claude.UsageLimit{Utilization: 10}, LastUpdated: now.Add(-8 * time.Hour)})
+	appendHistory(&claude.UsageLimits{FiveHour: &claude.UsageLimit{Utilization: 30}, LastUpdated: now.Add(-6 * time.Hour)})
+
+	// Flat for the whole window: the reading in effect still counts
+	entries := recentHistory(now)
+	if len(entries) != 1 || entries[0].Limits.FiveHour.Utilization != 30 || !entries[0].Timestamp.Equal(now.Add(-recentHistoryWindow)) {
+		t.Fatalf("recentHistory() with no changes in the window = %+v, want the 30%% reading from the window start", entries)
+	}
+
+	// Appended entries join without re-reading the file
+	appendHistory(&claude.UsageLimits{FiveHour: &claude.UsageLimit{Utilization: 50}, LastUpdated: now.Add(-time.Hour)})
+	os.Remove(historyFile)
+	summary, ok := summarizeHistory(recentHistory(now), func(l *claude.UsageLimits) *claude.UsageLimit { return l.FiveHour }, now)
+	if !ok || summary.Min != 30 || summary.Max != 50 || summary.Avg != 34 {
+		t.Errorf("summary of the recent history = %+v, %v, want 30–50%%, avg 34%%", summary, ok)
+	}
+}
+
+func TestSummarizeHistory(t *testing.T) {
+	start := time.Date(2025, 6, 12, 12, 0, 0, 0, time.UTC)
+	entry := func(minutes int, fiveHour float64) historyEntry {
+		return historyEntry{
+			Timestamp: start.Add(time.Duration(minutes) * time.Minute),
+			Limits:    &claude.UsageLimits{FiveHour: &claude.UsageLimit{Utilization: fiveHour}},
+		}
+	}
+	fiveHour := func(l *claude.UsageLimits) *claude.UsageLimit { return l.FiveHour }
+	sevenDay := func(l *claude.UsageLimits) *claude.UsageLimit { return l.SevenDay }
+
+	// 10% for 30 minutes, then 40% for 90 minutes
+	entries := []historyEntry{entry(0, 10), entry(30, 40)}
+	now := start.Add(2 * time.Hour)
+
+	got, ok := summarizeHistory(entries, fiveHour, now)
+	want := usageSummary{Min: 10, Max: 40, Avg: 32.5}
+	if !ok || got != want {
+		t.Errorf("summarizeHistory() = %+v, %v, want %+v, true", got, ok, want)
+	}
+
+	if _, ok := summarizeHistory(entries, sevenDay, now); ok {
+		t.Error("summarizeHistory() for a missing limit = true, want false")
+	}
+	if _, ok := summarizeHistory(nil, fiveHour, now); ok {
+		t.Error("summarizeHistory() with no entries = true, want false")
+	}
+}
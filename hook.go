@@ -0,0 +1,73 @@
+// hook.go - User command run when a limit crosses its critical threshold
+
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+// Long enough for a webhook post; a hung command is killed after this
+const criticalHookTimeout = 30 * time.Second
+
+// runCriticalHook runs criticalHookCommand for each limit that moved into
+// the red band since the previous fetch (or is in it on the first fetch)
+func runCriticalHook(event UsageUpdated) {
+	config := currentConfig()
+	if config.CriticalHookCommand == "" {
+		return
+	}
+
+	for _, kind := range limitKinds {
+		limit := kind.Get(event.Limits)
+		if limit == nil || !crossedCritical(kind.Get(event.Previous), limit, kind.Key) {
+			continue
+		}
+		// Asynchronous so a slow command can't hold up the refresh loop
+		go runHookCommand(config.CriticalHookCommand, criticalHookEnv(kind, limit))
+	}
+}
+
+// Helper function to check whether a limit entered the red band between two
+// fetches. A missing previous reading counts as below it.
+func crossedCritical(previous, current *claude.UsageLimit, limitType string) bool {
+	if getUsageLevel(current.Utilization, limitType) != levelHigh {
+		return false
+	}
+	return previous == nil || getUsageLevel(previous.Utilization, limitType) != levelHigh
+}
+
+// Helper function to describe a limit to the hook through its environment
+func criticalHookEnv(kind limitKind, limit *claude.UsageLimit) []string {
+	_, red := colorThresholds(kind.Key)
+	resetsAt := ""
+	if !limit.ResetsAtTime.IsZero() {
+		resetsAt = limit.ResetsAtTime.Format(time.RFC3339)
+	}
+	return []string{
+		"CLAUDE_LIMIT=" + kind.Key,
+		"CLAUDE_LIMIT_LABEL=" + kind.Label,
+		"CLAUDE_UTILIZATION=" + strconv.FormatFloat(limit.Utilization, 'f', -1, 64),
+		"CLAUDE_THRESHOLD=" + strconv.FormatFloat(red, 'f', -1, 64),
+		"CLAUDE_RESETS_AT=" + resetsAt,
+	}
+}
+
+// runHookCommand runs command through the shell with env added, logging
+// failures. Shutdown or the timeout kills it.
+func runHookCommand(command string, env []string) {
+	ctx, cancel := context.WithTimeout(appCtx, criticalHookTimeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, command)
+	cmd.Env = append(os.Environ(), env...)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		log.Printf("Warning: Critical hook failed: %v: %s\n", err, out)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+func TestCrossedCritical(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous *claude.UsageLimit
+		current  float64
+		want     bool
+	}{
+		{"below red", &claude.UsageLimit{Utilization: 50}, 70, false},
+		{"crossed", &claude.UsageLimit{Utilization: 70}, 85, true},
+		{"already red", &claude.UsageLimit{Utilization: 85}, 90, false},
+		{"first fetch red", nil, 90, true},
+		{"first fetch below", nil, 40, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := crossedCritical(tt.previous, &claude.UsageLimit{Utilization: tt.current}, "five_hour"); got != tt.want {
+				t.Errorf("crossedCritical() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunHookCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	oldCtx := appCtx
+	defer func() { appCtx = oldCtx }()
+	appCtx = context.Background()
+
+	out := filepath.Join(t.TempDir(), "out")
+	limit := &claude.UsageLimit{Utilization: 85.5, ResetsAtTime: time.Date(2025, 6, 12, 15, 0, 0, 0, time.UTC)}
+	env := criticalHookEnv(limitKinds[0], limit)
+	runHookCommand(`echo "$CLAUDE_LIMIT $CLAUDE_UTILIZATION $CLAUDE_THRESHOLD $CLAUDE_RESETS_AT" > "`+out+`"`, env)
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(string(data)), "five_hour 85.5 80 2025-06-12T15:00:00Z"; got != want {
+		t.Errorf("hook saw %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,156 @@
+// icon.go - Colored dot icons for the menu bar
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/getlantern/systray"
+)
+
+const (
+	iconSize      = 22 // Menu bar icons are drawn at 22pt on macOS
+	iconDotRadius = 7.0
+)
+
+// usageLevel is the band a utilization falls in, from the color thresholds
+type usageLevel int
+
+const (
+	levelNone usageLevel = iota // No data, idle, or an error
+	levelLow
+	levelMid
+	levelHigh
+)
+
+var levelColors = [...]color.NRGBA{
+	levelNone: {0x8e, 0x8e, 0x93, 0xff}, // gray
+	levelLow:  {0x34, 0xc7, 0x59, 0xff}, // green
+	levelMid:  {0xff, 0xcc, 0x00, 0xff}, // yellow
+	levelHigh: {0xff, 0x3b, 0x30, 0xff}, // red
+}
+
+// Icon data for each level, generated once by initMenuBarIcons, and a fully
+// transparent one to hide them
+var (
+	levelIcons [len(levelColors)][]byte
+	blankIcon  []byte
+)
+
+// Whether an icon is showing, so text mode knows to hide it
+var iconShown atomic.Bool
+
+// initMenuBarIcons renders the dot icons. Called before the first redraw.
+// If that fails the menu bar falls back to text.
+func initMenuBarIcons() {
+	var icons [len(levelColors)][]byte
+	for level, c := range levelColors {
+		data, err := renderDotIcon(c)
+		if err != nil {
+			log.Printf("Warning: Failed to render menu bar icons, showing text instead: %v\n", err)
+			return
+		}
+		icons[level] = data
+	}
+	blank, err := renderDotIcon(color.NRGBA{})
+	if err != nil {
+		log.Printf("Warning: Failed to render menu bar icons, showing text instead: %v\n", err)
+		return
+	}
+	levelIcons, blankIcon = icons, blank
+}
+
+// Helper function to get the menuBarDisplay in effect: "text" if the icons
+// couldn't be rendered
+func menuBarDisplay() string {
+	if blankIcon == nil {
+		return "text"
+	}
+	return currentConfig().MenuBarDisplay
+}
+
+// Helper function to check whether the menu bar shows the dot icon
+func menuBarUsesIcon() bool {
+	display := menuBarDisplay()
+	return display == "icon" || display == "iconAndText"
+}
+
+// Helper function to show the dot for level, or in text mode hide one left
+// over from an icon mode before a reload. systray can't remove an icon, so
+// a transparent one replaces it.
+func setLevelIcon(level usageLevel) {
+	if menuBarUsesIcon() {
+		systray.SetIcon(levelIcons[level])
+		iconShown.Store(true)
+	} else if iconShown.Swap(false) {
+		systray.SetIcon(blankIcon)
+	}
+}
+
+// setUsageTitle shows a usage title for level. In "icon" mode the dot
+// replaces the text, leaving only iconTitle, e.g. a stale data marker.
+func setUsageTitle(level usageLevel, title, iconTitle string) {
+	setLevelIcon(level)
+	if menuBarDisplay() == "icon" {
+		systray.SetTitle(iconTitle)
+	} else {
+		systray.SetTitle(title)
+	}
+}
+
+// setStatusTitle shows a status such as "Loading..." or "Expired". The text
+// stays visible in every mode since a gray dot alone wouldn't explain it.
+func setStatusTitle(emoji, text string) {
+	setLevelIcon(levelNone)
+	systray.SetTitle(statusTitle(emoji, text))
+}
+
+// renderDotIcon draws an anti-aliased dot of color c on a transparent
+// background, as PNG (wrapped in ICO on Windows, which requires it)
+func renderDotIcon(c color.NRGBA) ([]byte, error) {
+	img := image.NewNRGBA(image.Rect(0, 0, iconSize, iconSize))
+	center := iconSize / 2.0
+	for y := range iconSize {
+		for x := range iconSize {
+			// Coverage falls off over the pixel straddling the edge
+			dist := math.Hypot(float64(x)+0.5-center, float64(y)+0.5-center)
+			coverage := max(0, min(1, iconDotRadius+0.5-dist))
+			img.SetNRGBA(x, y, color.NRGBA{c.R, c.G, c.B, uint8(coverage * float64(c.A))})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	if runtime.GOOS == "windows" {
+		return wrapPNGInICO(buf.Bytes(), iconSize), nil
+	}
+	return buf.Bytes(), nil
+}
+
+// wrapPNGInICO returns a single-image ICO file holding pngData, which
+// Windows Vista and later accept in place of a bitmap
+func wrapPNGInICO(pngData []byte, size int) []byte {
+	const headerSize = 6 + 16 // ICONDIR plus one ICONDIRENTRY
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Reserved, Type, Count uint16
+	}{0, 1, 1})
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Width, Height, Colors, Reserved uint8
+		Planes, BitCount                uint16
+		BytesInRes, ImageOffset         uint32
+	}{uint8(size), uint8(size), 0, 0, 1, 32, uint32(len(pngData)), headerSize})
+	buf.Write(pngData)
+	return buf.Bytes()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/png"
+	"testing"
+)
+
+func TestRenderDotIcon(t *testing.T) {
+	data, err := renderDotIcon(levelColors[levelHigh])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.HasPrefix(data, []byte{0, 0, 1, 0}) {
+		// Windows build: skip past the ICO header to the embedded PNG
+		data = data[22:]
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("icon is not a PNG: %v", err)
+	}
+	if size := img.Bounds().Size(); size.X != iconSize || size.Y != iconSize {
+		t.Errorf("icon size = %v, want %dx%d", size, iconSize, iconSize)
+	}
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("corner alpha = %d, want transparent", a)
+	}
+	if r, _, _, a := img.At(iconSize/2, iconSize/2).RGBA(); a != 0xffff || r>>8 != 0xff {
+		t.Errorf("center = r %d a %d, want opaque red", r>>8, a)
+	}
+}
+
+func TestWrapPNGInICO(t *testing.T) {
+	pngData := []byte("\x89PNG fake")
+	ico := wrapPNGInICO(pngData, 22)
+
+	if got := binary.LittleEndian.Uint16(ico[2:]); got != 1 {
+		t.Errorf("type = %d, want 1 (icon)", got)
+	}
+	if got := binary.LittleEndian.Uint32(ico[14:]); got != uint32(len(pngData)) {
+		t.Errorf("image size = %d, want %d", got, len(pngData))
+	}
+	offset := binary.LittleEndian.Uint32(ico[18:])
+	if !bytes.Equal(ico[offset:], pngData) {
+		t.Errorf("image data at offset %d doesn't match", offset)
+	}
+}
+
+func TestGetUsageLevel(t *testing.T) {
+	tests := []struct {
+		utilization float64
+		limitType   string
+		want        usageLevel
+	}{
+		{10, "five_hour", levelLow},
+		{50, "five_hour", levelMid},
+		{95, "five_hour", levelHigh},
+		{40, "seven_day_opus", levelHigh},
+	}
+
+	setTestConfig(t, func(c *Config) {
+		c.ColorThresholds = map[string]ColorThresholds{"seven_day_opus": {Yellow: 20, Red: 40}}
+	})
+	for _, tt := range tests {
+		if got := getUsageLevel(tt.utilization, tt.limitType); got != tt.want {
+			t.Errorf("getUsageLevel(%v, %q) = %d, want %d", tt.utilization, tt.limitType, got, tt.want)
+		}
+	}
+}
@@ -1,87 +1,490 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/getlantern/systray"
+	"github.com/wickes1/claude-monitor-lite/claude"
 )
 
 const (
 	refreshInterval    = 30 * time.Second
-	pidCheckTimeout    = 500 * time.Millisecond
+	pidPollInterval    = 100 * time.Millisecond
 	pidFilePermissions = 0644 // Owner read/write, others read
+	usageBarSegments   = 5
+	usageDashboardURL  = "https://claude.ai/settings/usage"
+	debugHTTPEnv       = "CLAUDE_MONITOR_DEBUG_HTTP"
+	headlessEnv        = "CLAUDE_MONITOR_HEADLESS"
+	apiBaseURLEnv      = "CLAUDE_API_BASE_URL"
+
+	// Session check before starting the daemon; kept short so startup stays snappy
+	preflightTimeout = 5 * time.Second
+
+	// Below this a limit counts as unused rather than rounding to 0%
+	zeroUtilizationEpsilon = 0.01
+
+	// Labels for idle limits unless overridden in the config
+	defaultNoSessionLabel = "no active session"
+	defaultUnusedLabel    = "unused"
+
+	// Weekly limits resetting this close together share one countdown
+	weeklyResetTolerance = 5 * time.Minute
+
+	// Menu bar icons for states without usage to show, distinct so a
+	// transient blip is easy to tell from something needing action
+	iconNoData      = "⚪"
+	iconLoading     = "🔄"
+	iconError       = "⚠️"
+	iconAuthExpired = "🔑"
+	iconBlocked     = "🚫"
+
+	// Data older than this is flagged in the menu bar (staleAfterMinutes overrides)
+	defaultStaleAfter = 10 * time.Minute
+
+	// Reset times are shown rounded to this many minutes (resetPrecisionMinutes
+	// and resetPrecision override)
+	defaultResetPrecisionMinutes = 10
+
+	// Color indicator thresholds (percent)
+	yellowThreshold = 50.0
+	redThreshold    = 80.0
+
+	// A reset time further in the past than this means the API sent stale data
+	staleResetGrace = time.Minute
+
+	// How long shutdown waits for in-flight fetches
+	shutdownTimeout = 3 * time.Second
+
+	// How long stop waits for the monitor to exit
+	stopTimeout = 5 * time.Second
+
+	// Startup warm-up while the network may not be ready yet
+	startupRetryAttempts = 5
+	startupRetryDelay    = 3 * time.Second
+
+	// Pastes the interactive login accepts before giving up
+	loginAttempts = 3
 )
 
-var (
-	// Menu items (also serve as indicator selectors)
-	mCurrentSession *systray.MenuItem
-	mWeeklyAll      *systray.MenuItem
-	mWeeklyOpus     *systray.MenuItem
+// Exit codes. Scripts branch on these, so existing values must not change.
+const (
+	exitOK               = 0
+	exitError            = 1 // Anything not covered below
+	exitUsage            = 2 // Bad command or flags (also used by the flag package)
+	exitNotAuthenticated = 3 // No session, or the session was rejected
+	exitNetworkError     = 4 // claude.ai unreachable or blocked
+	exitAlreadyRunning   = 5
+	exitNotRunning       = 6
+)
 
-	// Refresh button
+// Values of fetchState
+const (
+	fetchStateUnknown int32 = iota
+	fetchStateOK
+	fetchStateFailed
+)
+
+// errLoginIncomplete marks a login the user didn't finish (no key entered)
+var errLoginIncomplete = errors.New("login not completed")
+
+var (
+	// Usage menu items (also serve as indicator selectors), one per entry in
+	// limitItemKinds: every limit, those in menuKinds (the limits listed in
+	// displayLimits when the menu was built) first. The rest stay hidden
+	// unless the menu bar shows one.
+	mLimitItems    []*systray.MenuItem
+	limitItemKinds []limitKind
+	menuKinds      []limitKind
+
+	// Replaces the usage while the last fetch failed
+	mFetchError *systray.MenuItem
+
+	// Reset Times submenu, one item per entry in menuKinds
+	mResetTimes *systray.MenuItem
+	mResetItems []*systray.MenuItem
+
+	// Recent submenu: a usage range per entry in menuKinds, or mRecentEmpty
+	mRecent      *systray.MenuItem
+	mRecentItems []*systray.MenuItem
+	mRecentEmpty *systray.MenuItem
+
+	// Account header (hidden until the profile is known) and the re-login
+	// hint for an aging session
+	mAccount    *systray.MenuItem
+	mPlan       *systray.MenuItem
+	mSessionAge *systray.MenuItem
+
+	// Refresh and pause buttons
 	mRefresh *systray.MenuItem
+	mPause   *systray.MenuItem
+
+	// Shown once a newer release is found. The release page is set by the
+	// update check's goroutine and read on the menu loop.
+	mUpdate   *systray.MenuItem
+	updateURL atomic.Pointer[string]
+
+	// Used/remaining toggle
+	mShowRemaining *systray.MenuItem
+
+	// Notification snooze submenu
+	mSnooze       *systray.MenuItem
+	mSnoozeCancel *systray.MenuItem
 
 	// App config
-	appConfig    Config
+	appConfig    atomic.Pointer[Config] // Read with currentConfig, change with updateAppConfig
 	pidFile      string
-	claudeClient *ClaudeUsageClient
+	socketFile   string
+	fifoFile     string
+	historyFile  string
+	budgetFile   string
+	logFile      string
+	claudeClient atomic.Pointer[claude.ClaudeUsageClient] // Replaced when sessionKeyCommand gives a new key
 
 	// Last fetched limits for instant display switching (protected by mutex)
-	lastLimits  *UsageLimits
+	lastLimits  *claude.UsageLimits
 	limitsMutex sync.RWMutex
 
-	// Context for graceful shutdown
+	// Outcome of the last fetch, for logging changes (fetchStateUnknown at start)
+	fetchState atomic.Int32
+
+	// Unix minute of the last usage redraw, 0 after an error replaced it
+	drawnMinute atomic.Int64
+
+	// Stale reset time (Unix seconds) already re-fetched for, so a stuck
+	// API value triggers one extra fetch rather than a loop
+	staleRefetchedFor atomic.Int64
+
+	// Context for graceful shutdown, canceled by cleanup
 	appCtx    context.Context
 	appCancel context.CancelFunc
+
+	// Makes cleanup run once however shutdown was triggered
+	cleanupOnce sync.Once
+
+	// In-flight fetch goroutines, awaited on shutdown
+	fetchWG sync.WaitGroup
+
+	// Checks once per run that a lazily resolved org ID made it to the config
+	orgIDSaved sync.Once
+
+	// Polling is skipped while paused (read from fetch goroutines)
+	isPaused atomic.Bool
+
+	// Set by --quiet to suppress informational output
+	quietMode bool
+
+	// Set by --ignore-preflight to start despite a failed session check
+	ignorePreflight bool
+
+	// Set by --replace to stop a running monitor and start in its place
+	replaceRunning bool
+
+	// Set by --headless or the headless config key: poll without the menu bar
+	headlessMode bool
+
+	// SIGHUP notifications, handled on the refresh loop goroutine
+	reloadChan = make(chan os.Signal, 1)
+
+	// Snooze menu selections (0 cancels), handled on the refresh loop goroutine
+	snoozeChan = make(chan time.Duration)
+
+	// Usage item clicks, as menuBarIndicator values, handled on the refresh loop goroutine
+	indicatorChan = make(chan string)
 )
 
+// exitCodeFor maps a failed login or fetch to its exit code. Only failures
+// worth retrying later (no response, or a Cloudflare block) are network
+// errors; a bad base URL or an unexpected response is not.
+func exitCodeFor(err error) int {
+	if errors.Is(err, errLoginIncomplete) || isAuthError(err) {
+		return exitNotAuthenticated
+	}
+	switch claude.CategoryOf(err) {
+	case claude.CategoryNetwork, claude.CategoryBlocked:
+		return exitNetworkError
+	default:
+		return exitError
+	}
+}
+
+// Helper function to explain why a pasted key couldn't be validated. Only a
+// rejected key is the user's typo; anything else is claude.ai or the network,
+// so pasting again wouldn't help.
+func reportValidationFailure(err error) error {
+	switch {
+	case isAuthError(err):
+		fmt.Fprintf(os.Stderr, "Session validation failed: %v\n", err)
+		fmt.Fprintln(os.Stderr, "The session key may be invalid. Please try again.")
+	case errors.Is(err, claude.ErrBlocked):
+		fmt.Fprintf(os.Stderr, "Could not check the session key: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Open https://claude.ai in your browser, then run claude-monitor-lite again. The key was saved.")
+	default:
+		fmt.Fprintf(os.Stderr, "Could not check the session key: %v\n", err)
+		fmt.Fprintln(os.Stderr, "This looks like a network problem, not a bad key. Check your connection and run claude-monitor-lite again. The key was saved.")
+	}
+	return err
+}
+
+// Helper function to check whether err means the session was rejected
+func isAuthError(err error) bool {
+	return errors.Is(err, claude.ErrAuthFailed) || errors.Is(err, claude.ErrSessionExpired)
+}
+
+// Serializes appConfig writers so concurrent updates don't drop each other
+var appConfigWriteMutex sync.Mutex
+
+// currentConfig returns the settings in effect. Goroutines share it, so it
+// must be treated as read-only: changes go through updateAppConfig, which
+// swaps in a modified copy. Before any config is loaded, it's all zero values.
+func currentConfig() *Config {
+	if config := appConfig.Load(); config != nil {
+		return config
+	}
+	return &Config{}
+}
+
+// setAppConfig replaces the settings in effect, e.g. after a reload
+func setAppConfig(config Config) {
+	appConfigWriteMutex.Lock()
+	defer appConfigWriteMutex.Unlock()
+	appConfig.Store(&config)
+}
+
+// updateAppConfig applies mutate to a copy of the settings in effect and
+// makes the copy current, returning it. The file on disk is unchanged; see
+// UpdateConfig.
+func updateAppConfig(mutate func(*Config)) *Config {
+	appConfigWriteMutex.Lock()
+	defer appConfigWriteMutex.Unlock()
+	config := *currentConfig()
+	mutate(&config)
+	appConfig.Store(&config)
+	return &config
+}
+
 // Helper function to create Claude client from session
-func createClientFromSession(session *AuthSession) *ClaudeUsageClient {
+func createClientFromSession(session *AuthSession) *claude.ClaudeUsageClient {
+	config := currentConfig()
+	var client *claude.ClaudeUsageClient
 	if session.OrganizationID != "" {
-		return NewClaudeUsageClientWithOrg(session.SessionKey, session.OrganizationID)
+		client = claude.NewClaudeUsageClientWithOrg(session.SessionKey, session.OrganizationID)
+	} else {
+		client = claude.NewClaudeUsageClient(session.SessionKey)
+	}
+	if config.DisableKeepAlives {
+		client.SetDisableKeepAlives(true)
+	}
+	client.SetTLSConfig(loadTLSConfig())
+	if err := client.SetIPVersion(claude.IPVersion(config.IPVersion)); err != nil {
+		log.Printf("Warning: %v, using both\n", err)
+	}
+	client.SetUserAgent(config.UserAgent)
+	client.SetBaseURL(os.Getenv(apiBaseURLEnv))
+	if err := client.SetAuthMode(claude.AuthMode(config.AuthMode)); err != nil {
+		log.Printf("Warning: %v, using cookie auth\n", err)
+	}
+	if os.Getenv(debugHTTPEnv) == "1" {
+		client.SetDebugLog(log.Printf)
+	}
+	return client
+}
+
+// Helper function to load the configured CA bundle and client certificate.
+// If a file can't be loaded, the error is logged and the system CAs are used,
+// so the log explains the certificate errors that follow.
+func loadTLSConfig() *tls.Config {
+	config := currentConfig()
+	tlsConfig, err := claude.LoadTLSConfig(config.CAFile, config.ClientCertFile, config.ClientKeyFile)
+	if err != nil {
+		log.Printf("Warning: %v\n", err)
+		return nil
 	}
-	return NewClaudeUsageClient(session.SessionKey)
+	return tlsConfig
 }
 
-// Helper function to round utilization to nearest integer
+// Helper function to round utilization to an integer using the configured
+// rounding ("round" half-up by default, "floor" or "ceil")
 func roundUtilization(utilization float64) int {
-	return int(utilization + 0.5)
+	switch currentConfig().UtilizationRounding {
+	case "floor":
+		return int(math.Floor(utilization))
+	case "ceil":
+		return int(math.Ceil(utilization))
+	default:
+		return int(utilization + 0.5)
+	}
+}
+
+// Helper function to format a percentage's number. Where rounding would
+// claim the limit is hit but it isn't quite, one decimal is shown instead,
+// rounded away from the limit: 99.6% used is "99.6", and with remaining set
+// (percent is what's left) 0.4% left is "0.4".
+func formatPercentNumber(percent float64, remaining bool) string {
+	n := roundUtilization(percent)
+	nearFull := n >= 100 && percent < 100
+	nearEmpty := remaining && n <= 0 && percent > 0
+	switch {
+	case nearFull:
+		return strconv.FormatFloat(math.Floor(percent*10)/10, 'f', 1, 64)
+	case nearEmpty:
+		return strconv.FormatFloat(math.Max(math.Floor(percent*10)/10, 0.1), 'f', 1, 64)
+	default:
+		return strconv.Itoa(n)
+	}
+}
+
+// Helper function to get the percentage to display: utilization, or what's
+// left of the limit when showRemaining is set
+func displayPercent(utilization float64) float64 {
+	if currentConfig().ShowRemaining {
+		return max(0, 100-utilization)
+	}
+	return utilization
+}
+
+// Helper function to format the number formatPercent shows: utilization, or
+// what's left of the limit when showRemaining is set
+func formatDisplayPercentNumber(utilization float64) string {
+	if currentConfig().ShowRemaining {
+		return formatPercentNumber(max(0, 100-utilization), true)
+	}
+	return formatPercentNumber(utilization, false)
+}
+
+// Helper function to format the displayed percentage, e.g. "42%" or "58% left"
+func formatPercent(utilization float64) string {
+	if currentConfig().ShowRemaining {
+		return fmt.Sprintf("%s%% left", formatPercentNumber(max(0, 100-utilization), true))
+	}
+	return fmt.Sprintf("%s%%", formatPercentNumber(utilization, false))
+}
+
+// Helper function to get color indicator based on utilization, using the
+// thresholds configured for limitType (e.g. "seven_day_opus")
+func getColorIndicator(utilization float64, limitType string) string {
+	indicators := colorIndicators()
+	switch getUsageLevel(utilization, limitType) {
+	case levelLow:
+		return indicators.Low
+	case levelMid:
+		return indicators.Mid
+	default:
+		return indicators.High
+	}
+}
+
+// Helper function to get the usage band for utilization, using the
+// thresholds configured for limitType
+func getUsageLevel(utilization float64, limitType string) usageLevel {
+	yellow, red := colorThresholds(limitType)
+	if utilization < yellow {
+		return levelLow
+	}
+	if utilization < red {
+		return levelMid
+	}
+	return levelHigh
+}
+
+// Helper function to get the indicator glyphs: the configured ones, or the
+// default dots (text markers when emoji are off)
+func colorIndicators() ColorIndicators {
+	config := currentConfig()
+	if config.ColorIndicators != (ColorIndicators{}) {
+		return config.ColorIndicators
+	}
+	if config.UseEmoji {
+		return emojiColorIndicators
+	}
+	return textColorIndicators
+}
+
+// Helper function to get the yellow/red thresholds for a limit type,
+// falling back to the global thresholds for anything not overridden
+func colorThresholds(limitType string) (yellow, red float64) {
+	yellow, red = yellowThreshold, redThreshold
+	override := currentConfig().ColorThresholds[limitType]
+	if override.Yellow > 0 {
+		yellow = override.Yellow
+	}
+	if override.Red > 0 {
+		red = override.Red
+	}
+	return yellow, red
+}
+
+// Helper function to build a menu bar status title, e.g. "⚪ Error"
+func statusTitle(emoji, text string) string {
+	if currentConfig().UseEmoji {
+		return emoji + " " + text
+	}
+	return text
 }
 
-// Helper function to get color indicator based on utilization
-func getColorIndicator(utilization float64) string {
-	if utilization < 50.0 {
-		return "🟢"
+// Helper function to render utilization as a segmented bar, e.g. "🟩🟩🟩⬜⬜"
+// or "[###--]" without emoji
+func renderUsageBar(utilization float64, useEmoji bool) string {
+	filled := int(utilization/100*usageBarSegments + 0.5)
+	filled = max(0, min(filled, usageBarSegments))
+	if !useEmoji {
+		return "[" + strings.Repeat("#", filled) + strings.Repeat("-", usageBarSegments-filled) + "]"
 	}
-	if utilization < 80.0 {
-		return "🟡"
+	return strings.Repeat("🟩", filled) + strings.Repeat("⬜", usageBarSegments-filled)
+}
+
+// Helper function to round minutes past the hour to the nearest multiple of
+// precision, or to the next hour (60) when that is nearer, also for a
+// precision that doesn't divide 60 (59 rounds to 60 for 7, not 56). Halfway
+// rounds up. Callers carry 60 into the hour.
+func roundMinutes(minutes, precision int) int {
+	down := minutes / precision * precision
+	up := min(down+precision, 60)
+	if minutes-down < up-minutes {
+		return down
 	}
-	return "🔴"
+	return up
 }
 
-// Helper function to round minutes to nearest 10
-func roundToTenMinutes(minutes int) int {
-	return ((minutes + 5) / 10) * 10
+// Helper function to get the minutes limitType's reset time is rounded to:
+// its resetPrecision entry, else resetPrecisionMinutes, else 10. Values
+// below 1 are skipped and anything over an hour is treated as an hour.
+func resetPrecision(limitType string) int {
+	config := currentConfig()
+	for _, precision := range []int{config.ResetPrecision[limitType], config.ResetPrecisionMinutes} {
+		if precision >= 1 {
+			return min(precision, 60)
+		}
+	}
+	return defaultResetPrecisionMinutes
 }
 
-// Helper function to calculate time until reset
-func calculateTimeUntilReset(resetTime time.Time) (hours, minutes int, valid bool) {
+// Helper function to calculate time from now until reset
+func calculateTimeUntilReset(resetTime, now time.Time) (hours, minutes int, valid bool) {
 	if resetTime.IsZero() {
 		return 0, 0, false
 	}
 
 	// Truncate current time to the minute (ignore seconds)
-	now := time.Now()
 	nowTruncated := time.Date(now.Year(), now.Month(), now.Day(),
 		now.Hour(), now.Minute(), 0, 0, now.Location())
 
@@ -96,68 +499,203 @@ func calculateTimeUntilReset(resetTime time.Time) (hours, minutes int, valid boo
 	return totalMinutes / 60, totalMinutes % 60, true
 }
 
+// Helper function to format a countdown, omitting zero components:
+// "3h 20m", "40m", "3h". sep goes between hours and minutes.
+func formatDuration(hours, minutes int, sep string) string {
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dh%s%dm", hours, sep, minutes)
+	}
+}
+
+// Helper function to check whether a countdown of hours to limitType's reset
+// is shown in days: only weekly limits, and only with weeklyDaysAfterHours set
+// and at least a day to go
+func countsDownInDays(limitType string, hours int) bool {
+	after := currentConfig().WeeklyDaysAfterHours
+	return after > 0 && limitType != "five_hour" && hours >= max(after, 24)
+}
+
+// Helper function to format hours as days and hours, e.g. "4d 2h"
+func formatDays(hours int, sep string) string {
+	if hours%24 == 0 {
+		return fmt.Sprintf("%dd", hours/24)
+	}
+	return fmt.Sprintf("%dd%s%dh", hours/24, sep, hours%24)
+}
+
+// Helper function to format the time until limitType resets, in days for
+// far-off weekly resets when weeklyDaysAfterHours asks for it
+func formatCountdownFor(limitType string, hours, minutes int, sep string) string {
+	if countsDownInDays(limitType, hours) {
+		return formatDays(hours, sep)
+	}
+	return formatDuration(hours, minutes, sep)
+}
+
 // Helper function to format reset time for display
-func formatResetTime(resetTime time.Time) string {
+func formatResetTime(resetTime time.Time, limitType string) string {
 	local := resetTime.Local()
 
-	// Round minutes to nearest 10
-	roundedMinutes := roundToTenMinutes(local.Minute())
+	roundedMinutes := roundMinutes(local.Minute(), resetPrecision(limitType))
 
-	// Adjust hour if minutes rolled over
-	hour := local.Hour()
-	if roundedMinutes >= 60 {
-		hour = (hour + 1) % 24
-		roundedMinutes = 0
-	}
+	// Add the rounded minutes to the hour so a rollover to 60 carries
+	// into the hour, day, month and year
+	rounded := time.Date(local.Year(), local.Month(), local.Day(),
+		local.Hour(), 0, 0, 0, local.Location()).Add(time.Duration(roundedMinutes) * time.Minute)
 
-	return fmt.Sprintf("%04d-%02d-%02d %02d:%02d",
-		local.Year(), local.Month(), local.Day(), hour, roundedMinutes)
+	return rounded.Format("2006-01-02 15:04")
 }
 
-// Helper function to format a single usage limit with reset time
-func formatUsageWithReset(limit *UsageLimit, label string) string {
+// Helper function to format a single usage limit for its menu item.
+// Reset times live in the Reset Times submenu.
+func formatUsageItem(limit *claude.UsageLimit, label, limitType string) string {
 	if limit == nil {
 		return fmt.Sprintf("%s --", label)
 	}
 
-	utilization := roundUtilization(limit.Utilization)
-	hours, minutes, hasTime := calculateTimeUntilReset(limit.ResetsAtTime)
+	percent := formatPercent(limit.Utilization)
 
-	// Special case: no active session (0% with no reset time)
-	if !hasTime && utilization == 0 {
-		return fmt.Sprintf("%s %d%% (no active session)", label, utilization)
+	// Special case: no session or weekly usage yet (0% with no reset time)
+	if idle := idleLimitLabel(limit, limitType); idle != "" {
+		return fmt.Sprintf("%s %s (%s)", label, percent, idle)
 	}
 
-	if hasTime {
-		return fmt.Sprintf("%s %d%% (resets %s, in %dh %dm)",
-			label, utilization, formatResetTime(limit.ResetsAtTime), hours, minutes)
+	return fmt.Sprintf("%s %s", label, percent)
+}
+
+// Helper function to format a limit's reset time and countdown for the
+// Reset Times submenu
+func formatResetItem(limit *claude.UsageLimit, label, limitType string) string {
+	hours, minutes, hasTime := calculateTimeUntilReset(limit.ResetsAtTime, time.Now())
+	if !hasTime {
+		return fmt.Sprintf("%s: not scheduled", label)
+	}
+	return fmt.Sprintf("%s: %s (in %s)",
+		label, formatResetTime(limit.ResetsAtTime, limitType), formatCountdownFor(limitType, hours, minutes, " "))
+}
+
+// Helper function to check for no active session: 0% with no reset time,
+// as opposed to a genuine 0% partway through a window
+func isNoActiveSession(limit *claude.UsageLimit) bool {
+	_, _, hasTime := calculateTimeUntilReset(limit.ResetsAtTime, time.Now())
+	return !hasTime && roundUtilization(limit.Utilization) == 0
+}
+
+// Helper function to label a limit with nothing to count down: the 5-hour
+// window before a session starts, or a weekly limit unused since it last
+// reset. A weekly 0% with a reset time is a fresh window and gets no label.
+// Returns "" when the limit isn't idle.
+func idleLimitLabel(limit *claude.UsageLimit, limitType string) string {
+	if limit == nil {
+		return ""
 	}
+	config := currentConfig()
 
-	return fmt.Sprintf("%s %d%%", label, utilization)
+	if limitType == "five_hour" {
+		if isNoActiveSession(limit) {
+			return cmp.Or(config.NoSessionLabel, defaultNoSessionLabel)
+		}
+		return ""
+	}
+
+	_, _, hasTime := calculateTimeUntilReset(limit.ResetsAtTime, time.Now())
+	if !hasTime && limit.Utilization < zeroUtilizationEpsilon {
+		return cmp.Or(config.UnusedLabel, defaultUnusedLabel)
+	}
+	return ""
+}
+
+// Helper function to build the multi-line tooltip summarizing every limit
+func formatTooltip(limits *claude.UsageLimits) string {
+	weeklyReset, combineWeekly := sharedWeeklyReset(limits)
+	now := time.Now()
+
+	lines := []string{"Claude Monitor Lite"}
+	for _, kind := range displayedLimitKinds() {
+		limit := kind.Get(limits)
+		if limit == nil {
+			lines = append(lines, fmt.Sprintf("%s: --", kind.Label))
+			continue
+		}
+
+		line := fmt.Sprintf("%s: %s", kind.Label, formatPercent(limit.Utilization))
+		isWeekly := kind.Key != "five_hour"
+		if hours, minutes, ok := calculateTimeUntilReset(limit.ResetsAtTime, now); ok && !(combineWeekly && isWeekly) {
+			line += fmt.Sprintf(" (resets in %s)", formatCountdownFor(kind.Key, hours, minutes, " "))
+		}
+		lines = append(lines, line)
+	}
+
+	if combineWeekly {
+		if hours, minutes, ok := calculateTimeUntilReset(weeklyReset, now); ok {
+			lines = append(lines, fmt.Sprintf("Weekly resets in %s", formatCountdownFor("seven_day", hours, minutes, " ")))
+		}
+	}
+	if line := formatBurnRate(burn, limits.FiveHour, now); line != "" && isLimitDisplayed("five_hour") {
+		lines = append(lines, line)
+	}
+	if isStale(limits, time.Now()) {
+		lines = append(lines, "Last updated "+limits.LastUpdated.Local().Format("15:04"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Helper function to find a reset time shared by both weekly limits.
+// Returns false when the combined view is disabled or the resets differ.
+func sharedWeeklyReset(limits *claude.UsageLimits) (time.Time, bool) {
+	if !currentConfig().CombineWeeklyResets || limits.SevenDay == nil || limits.SevenDayOpus == nil {
+		return time.Time{}, false
+	}
+	if !isLimitDisplayed("seven_day") || !isLimitDisplayed("seven_day_opus") {
+		return time.Time{}, false
+	}
+
+	all, opus := limits.SevenDay.ResetsAtTime, limits.SevenDayOpus.ResetsAtTime
+	if all.IsZero() || opus.IsZero() {
+		return time.Time{}, false
+	}
+
+	diff := all.Sub(opus)
+	if diff < -weeklyResetTolerance || diff > weeklyResetTolerance {
+		return time.Time{}, false
+	}
+	return all, true
 }
 
 // Helper function to format usage limit for console display
-func formatConsoleUsage(limit *UsageLimit, label string, noSessionMsg string) string {
+func formatConsoleUsage(limit *claude.UsageLimit, label, limitType, idleLabel string) string {
 	if limit == nil {
 		return fmt.Sprintf("%s  --\n", label)
 	}
 
-	utilization := roundUtilization(limit.Utilization)
-	hours, minutes, hasTime := calculateTimeUntilReset(limit.ResetsAtTime)
+	percent := fmt.Sprintf("%3s%%", formatDisplayPercentNumber(limit.Utilization))
+	if currentConfig().ShowRemaining {
+		percent += " left"
+	}
+	hours, minutes, hasTime := calculateTimeUntilReset(limit.ResetsAtTime, time.Now())
 
+	if hasTime && countsDownInDays(limitType, hours) {
+		// Days out, the exact time is more than anyone needs
+		return fmt.Sprintf("%s  %s  (resets in %s)\n", label, percent, formatDays(hours, " "))
+	}
 	if hasTime {
-		return fmt.Sprintf("%s  %3d%%  (resets %s, in %dh %dm)\n",
-			label, utilization, formatResetTime(limit.ResetsAtTime), hours, minutes)
+		return fmt.Sprintf("%s  %s  (resets %s, in %s)\n",
+			label, percent, formatResetTime(limit.ResetsAtTime, limitType), formatDuration(hours, minutes, " "))
 	}
 
-	if noSessionMsg != "" {
-		return fmt.Sprintf("%s  %3d%%  (%s)\n", label, utilization, noSessionMsg)
+	if idleLabel != "" {
+		return fmt.Sprintf("%s  %s  (%s)\n", label, percent, idleLabel)
 	}
-	return fmt.Sprintf("%s  %3d%%\n", label, utilization)
+	return fmt.Sprintf("%s  %s\n", label, percent)
 }
 
 // Helper function to get the selected limit based on indicator setting
-func getSelectedLimit(limits *UsageLimits, indicator string) *UsageLimit {
+func getSelectedLimit(limits *claude.UsageLimits, indicator string) *claude.UsageLimit {
 	switch indicator {
 	case "currentSession":
 		return limits.FiveHour
@@ -170,139 +708,559 @@ func getSelectedLimit(limits *UsageLimits, indicator string) *UsageLimit {
 	}
 }
 
-// Helper function to display usage stats
-func displayUsageStats(limits *UsageLimits) {
-	fmt.Println("=== Current Usage ===")
-	fmt.Print(formatConsoleUsage(limits.FiveHour, "5-Hour Session:", "no active session"))
-	fmt.Print(formatConsoleUsage(limits.SevenDay, "Weekly (All):", ""))
-	fmt.Print(formatConsoleUsage(limits.SevenDayOpus, "Weekly (Opus):", ""))
-	fmt.Println()
+// Helper function to get the limit type for an indicator setting
+func selectedLimitType(indicator string) string {
+	switch indicator {
+	case "weeklyAll":
+		return "seven_day"
+	case "weeklyOpus":
+		return "seven_day_opus"
+	default:
+		return "five_hour"
+	}
 }
 
-// Helper function to update menu bar display
-func updateMenuBarDisplay(limits *UsageLimits) {
-	limit := getSelectedLimit(limits, appConfig.MenuBarIndicator)
+// limitKind describes a displayed limit, keyed by its API field name
+type limitKind struct {
+	Key        string
+	Label      string
+	ShortLabel string // For the one-line output, e.g. "5h"
+	Indicator  string // menuBarIndicator value that selects it
+	Field      func(*claude.UsageLimits) **claude.UsageLimit
+}
 
-	if limit == nil {
-		systray.SetTitle("⚪ --")
-		return
+var limitKinds = []limitKind{
+	{"five_hour", "5-Hour Session", "5h", "currentSession", func(l *claude.UsageLimits) **claude.UsageLimit { return &l.FiveHour }},
+	{"seven_day", "Weekly (All)", "7d", "weeklyAll", func(l *claude.UsageLimits) **claude.UsageLimit { return &l.SevenDay }},
+	{"seven_day_opus", "Weekly (Opus)", "opus", "weeklyOpus", func(l *claude.UsageLimits) **claude.UsageLimit { return &l.SevenDayOpus }},
+}
+
+// Get returns the kind's limit from limits, or nil if either is missing
+func (k limitKind) Get(limits *claude.UsageLimits) *claude.UsageLimit {
+	if limits == nil {
+		return nil
 	}
+	return *k.Field(limits)
+}
 
-	utilization := roundUtilization(limit.Utilization)
-	hours, minutes, hasTime := calculateTimeUntilReset(limit.ResetsAtTime)
-	indicator := getColorIndicator(limit.Utilization)
+// Helper function to get the limits to display, in order: those named in
+// displayLimits, or every limit when it's empty. Names are validated when
+// the config loads.
+func displayedLimitKinds() []limitKind {
+	config := currentConfig()
+	if len(config.DisplayLimits) == 0 {
+		return limitKinds
+	}
 
-	if hasTime {
-		systray.SetTitle(fmt.Sprintf("%s %d%% (%dh%dm)", indicator, utilization, hours, minutes))
-	} else {
-		systray.SetTitle(fmt.Sprintf("%s %d%%", indicator, utilization))
+	var kinds []limitKind
+	for _, key := range config.DisplayLimits {
+		if kind, ok := findLimitKind(key); ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(kinds) == 0 {
+		return limitKinds
 	}
+	return kinds
 }
 
-func main() {
-	appConfig = LoadConfig()
+// Helper function to look up a limit kind by its API key
+func findLimitKind(key string) (limitKind, bool) {
+	for _, kind := range limitKinds {
+		if kind.Key == key {
+			return kind, true
+		}
+	}
+	return limitKind{}, false
+}
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		log.Fatal("Failed to get home directory:", err)
+// Helper function to check whether a limit is displayed
+func isLimitDisplayed(key string) bool {
+	for _, kind := range displayedLimitKinds() {
+		if kind.Key == key {
+			return true
+		}
 	}
-	pidFile = filepath.Join(homeDir, ".claude-monitor-lite.pid")
+	return false
+}
 
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
-		case "stop":
-			handleStop()
-		case "logout":
-			handleLogout()
-		case "help", "--help", "-h":
-			printUsage()
-			os.Exit(0)
-		default:
-			fmt.Fprintf(os.Stderr, "Unknown command: %s\n", os.Args[1])
-			printUsage()
-			os.Exit(1)
+// Helper function to display usage stats
+func displayUsageStats(limits *claude.UsageLimits) {
+	if !quietMode {
+		writeUsageStats(os.Stdout, limits)
+	}
+}
+
+// writeUsageStats writes the human-readable usage summary, the text format
+func writeUsageStats(w io.Writer, limits *claude.UsageLimits) {
+	fmt.Fprintln(w, "=== Current Usage ===")
+	if plan := currentConfig().AccountPlan; plan != "" {
+		// Which plan decides what the percentages are of
+		fmt.Fprintf(w, "Plan: %s\n", plan)
+	}
+	weeklyReset, combineWeekly := sharedWeeklyReset(limits)
+
+	for _, kind := range displayedLimitKinds() {
+		limit := kind.Get(limits)
+		label := kind.Label + ":"
+
+		if combineWeekly && kind.Key != "five_hour" {
+			// Both weekly limits reset together: the countdown is shown
+			// once below. They have a reset time, so neither is idle.
+			weekly := *limit
+			weekly.ResetsAtTime = time.Time{}
+			fmt.Fprint(w, formatConsoleUsage(&weekly, label, kind.Key, ""))
+			continue
+		}
+
+		fmt.Fprint(w, formatConsoleUsage(limit, label, kind.Key, idleLimitLabel(limit, kind.Key)))
+		if kind.Key == "five_hour" && limit != nil {
+			if line := formatBurnRate(recentBurnTracker(limits), limit, time.Now()); line != "" {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
 		}
+	}
+
+	if !combineWeekly {
+		fmt.Fprintln(w)
 		return
 	}
+	if hours, minutes, ok := calculateTimeUntilReset(weeklyReset, time.Now()); ok && countsDownInDays("seven_day", hours) {
+		fmt.Fprintf(w, "Weekly resets in %s\n", formatDays(hours, " "))
+	} else if ok {
+		fmt.Fprintf(w, "Weekly resets %s, in %s\n", formatResetTime(weeklyReset, "seven_day"), formatDuration(hours, minutes, " "))
+	}
+	fmt.Fprintln(w)
+}
 
-	// Default command (no args) - auto-handle everything
-	handleAutoStart()
+// Helper function to get the menu bar marker for values kept through a
+// failed fetch
+func retryingMarker() string {
+	if currentConfig().UseEmoji {
+		return "↻"
+	}
+	return "~"
 }
 
-func printUsage() {
-	fmt.Println("Claude Monitor Lite - Menu bar monitor for Claude usage")
-	fmt.Println()
-	fmt.Println("Usage:")
-	fmt.Println("  claude-monitor-lite           Auto-start (login if needed, show status if running)")
-	fmt.Println("  claude-monitor-lite stop      Stop the monitor")
-	fmt.Println("  claude-monitor-lite logout    Clear session and stop monitor")
-	fmt.Println("  claude-monitor-lite help      Show this help")
-	fmt.Println()
-	fmt.Println("First time? Just run: claude-monitor-lite")
+// Helper function to get the menu bar marker for an aging session
+func sessionAgingMarker() string {
+	if currentConfig().UseEmoji {
+		return "🗝"
+	}
+	return "*"
 }
 
-func handleAutoStart() {
-	// Check authentication first
-	_, err := LoadAuthSession()
-	if err != nil {
-		// Not logged in - run login flow
-		fmt.Println("⚠️  Not authenticated")
-		fmt.Println()
-		_, err = handleLoginFlow()
-		if err != nil {
-			os.Exit(1)
-		}
+// Helper function to update menu bar display
+func updateMenuBarDisplay(limits *claude.UsageLimits) {
+	config := currentConfig()
+	limit := getSelectedLimit(limits, config.MenuBarIndicator)
+
+	if limit == nil {
+		setUsageTitle(levelNone, statusTitle(iconNoData, "--"), "")
+		return
 	}
 
-	// Check if already running
-	if isRunning() {
-		// Already running - show status
-		handleStatusDisplay()
+	// Only the 5-hour window goes idle; weekly limits always show a value
+	limitType := selectedLimitType(config.MenuBarIndicator)
+	if limitType == "five_hour" && isNoActiveSession(limit) {
+		setUsageTitle(levelNone, statusTitle("💤", "idle"), "")
 		return
 	}
 
-	// Not running - show current usage then start it
-	session, err := LoadAuthSession()
-	if err == nil {
-		client := createClientFromSession(session)
-		if limits, err := client.GetUsageLimits(); err == nil {
-			displayUsageStats(limits)
+	hours, minutes, hasTime := calculateTimeUntilReset(limit.ResetsAtTime, time.Now())
+	// Color is based on usage either way, so green still means plenty left
+	level := getUsageLevel(limit.Utilization, limitType)
+	indicator := getColorIndicator(limit.Utilization, limitType)
+
+	value := formatPercent(limit.Utilization)
+	if config.MenuBarStyle == "bar" {
+		value = renderUsageBar(displayPercent(limit.Utilization), config.UseEmoji)
+	} else if config.FixedWidthMenuBar {
+		digits := len(formatDisplayPercentNumber(limit.Utilization))
+		value = padFigures(value, 3-digits)
+	}
+
+	title := fmt.Sprintf("%s %s", indicator, value)
+	if menuBarUsesIcon() {
+		// The icon already shows the color
+		title = value
+	}
+	if hasTime && countsDownInDays(limitType, hours) {
+		// At most 7 days, so "4d02h" keeps its width without padding
+		days := formatDays(hours, "")
+		if config.FixedWidthMenuBar {
+			days = fmt.Sprintf("%dd%02dh", hours/24, hours%24)
 		}
+		title += fmt.Sprintf(" (%s)", days)
+	} else if hasTime && config.FixedWidthMenuBar {
+		// Up to 5 hours for the session, up to 168 for the weekly limits
+		hourDigits := 3
+		if limitType == "five_hour" {
+			hourDigits = 1
+		}
+		title += fmt.Sprintf(" (%s)", formatFixedDuration(hours, minutes, hourDigits))
+	} else if hasTime {
+		title += fmt.Sprintf(" (%s)", formatDuration(hours, minutes, ""))
+	}
+	// Markers go around the usage, and are all "icon" mode shows of it
+	var before, after []string
+	if budgetExhausted(time.Now()) {
+		// Explained by the Refresh Now item
+		before = append(before, statusTitle("🪫", "[BUDGET]"))
 	}
+	if isStale(limits, time.Now()) {
+		before = append(before, statusTitle("⏳", "[STALE]"))
+	}
+	if _, aging := agingSessionDays(savedSessionTime(), time.Now()); aging {
+		// Explained by the menu's session age item
+		after = append(after, sessionAgingMarker())
+	}
+	if fetchFailures.streak() > 0 {
+		// The last fetch failed, but not often enough to show the error
+		after = append(after, retryingMarker())
+	}
+	setUsageTitle(level, strings.Join(slices.Concat(before, []string{title}, after), " "),
+		strings.Join(slices.Concat(before, after), " "))
+}
 
-	fmt.Println("⚙️  Starting Claude Monitor Lite...")
-	fmt.Println()
-	handleStart()
+// Helper function to format a duration as "2h05m", hours padded to hourDigits,
+// so the menu bar title keeps its width as the countdown ticks
+func formatFixedDuration(hours, minutes, hourDigits int) string {
+	return padFigures(fmt.Sprintf("%dh%02dm", hours, minutes), hourDigits-len(strconv.Itoa(hours)))
 }
 
-func handleLoginFlow() (*AuthSession, error) {
-	session, err := LoginWithBrowser()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Login failed: %v\n", err)
-		return nil, err
+// Helper function to left-pad s with n figure spaces, which are as wide as a
+// digit even in proportional menu bar fonts
+func padFigures(s string, n int) string {
+	if n <= 0 {
+		return s
 	}
+	return strings.Repeat("\u2007", n) + s
+}
 
-	// Test the session and fetch organization ID
-	client := NewClaudeUsageClient(session.SessionKey)
-	if err := client.TestSession(); err != nil {
-		fmt.Fprintf(os.Stderr, "Session validation failed: %v\n", err)
-		fmt.Println("The session key may be invalid. Please try again.")
-		return nil, err
+// Helper function to check whether limits are too old to pass as current.
+// LastUpdated is only set by a successful fetch.
+func isStale(limits *claude.UsageLimits, now time.Time) bool {
+	config := currentConfig()
+	staleAfter := defaultStaleAfter
+	if config.StaleAfterMinutes > 0 {
+		staleAfter = time.Duration(config.StaleAfterMinutes) * time.Minute
 	}
+	return !limits.LastUpdated.IsZero() && now.Sub(limits.LastUpdated) > staleAfter
+}
 
-	// Save the organization ID
-	session.OrganizationID = client.organizationID
-	if err := SaveAuthSession(session); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to save organization ID: %v\n", err)
+// Helper function to check whether a session saved at savedAt is old enough
+// that it may expire soon, returning its age in whole days. A zero savedAt
+// (age unknown) never warns.
+func agingSessionDays(savedAt, now time.Time) (int, bool) {
+	config := currentConfig()
+	if config.SessionWarnAfterDays <= 0 || savedAt.IsZero() {
+		return 0, false
 	}
+	days := int(now.Sub(savedAt) / (24 * time.Hour))
+	return days, days >= config.SessionWarnAfterDays
+}
 
-	fmt.Println("✓ Session validated successfully!")
+// Helper function to get when the session in use was saved by login, or zero
+// when that's unknown
+func savedSessionTime() time.Time {
+	config := currentConfig()
+	if config.SavedAt == nil || config.SessionKeyCommand != "" {
+		return time.Time{}
+	}
+	return *config.SavedAt
+}
+
+// Helper function to check whether two fetches report the same usage:
+// identical utilization and reset time for every limit
+func sameUsage(a, b *claude.UsageLimits) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	bLimits := b.All()
+	for i, limit := range a.All() {
+		other := bLimits[i]
+		if limit == nil || other == nil {
+			if limit != other {
+				return false
+			}
+			continue
+		}
+		if limit.Utilization != other.Utilization || !limit.ResetsAtTime.Equal(other.ResetsAtTime) {
+			return false
+		}
+	}
+	return true
+}
+
+func main() {
+	log.SetOutput(redactingWriter{os.Stderr})
+	args := parseGlobalFlags(os.Args[1:])
+	migrateLegacyFiles()
+	setAppConfig(LoadConfig())
+	headlessMode = currentConfig().Headless || os.Getenv(headlessEnv) == "1"
+
+	configPath, err := ResolveConfigPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	statePrefix, err := ResolveStatePrefix()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if err := ensureDataDirs(configPath, statePrefix); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create data directory: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	pidFile = statePrefix + ".pid"
+	socketFile = statePrefix + ".sock"
+	fifoFile = statePrefix + ".fifo"
+	historyFile = statePrefix + ".history.jsonl"
+	budgetFile = statePrefix + ".requests.json"
+	logFile = statePrefix + ".log"
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "login":
+			handleLogin(args[1:])
+		case "run":
+			handleRun()
+		case "stop":
+			handleStop()
+		case "logout":
+			handleLogout(args[1:])
+		case "pause":
+			handlePause(true)
+		case "resume":
+			handlePause(false)
+		case "export":
+			handleExport(args[1:])
+		case "diagnose":
+			handleDiagnose(args[1:])
+		case "update-check":
+			handleUpdateCheck()
+		case "raw":
+			handleRaw()
+		case "dashboard":
+			handleDashboard(args[1:])
+		case "fetch":
+			handleFetch(args[1:])
+		case "status":
+			handleStatus(args[1:])
+		case "org", "whoami":
+			handleOrg()
+		case "help", "--help", "-h":
+			printUsage()
+			os.Exit(exitOK)
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown command: %s\n", args[0])
+			printUsage()
+			os.Exit(exitUsage)
+		}
+		return
+	}
+
+	// Default command (no args) - auto-handle everything
+	handleAutoStart()
+}
+
+// parseGlobalFlags consumes flags before the subcommand and returns the rest
+func parseGlobalFlags(args []string) []string {
+	fs := flag.NewFlagSet("claude-monitor-lite", flag.ExitOnError)
+	fs.Usage = printUsage
+	configPath := fs.String("config", "", "Use an alternate config file")
+	fs.BoolVar(&quietMode, "quiet", false, "Suppress informational output")
+	debugHTTP := fs.Bool("debug-http", false, "Log API requests and responses (session key masked)")
+	headless := fs.Bool("headless", false, "Run without the menu bar (for servers with no display)")
+	fs.BoolVar(&ignorePreflight, "ignore-preflight", false, "Start even if claude.ai is unreachable or the session is invalid")
+	fs.BoolVar(&replaceRunning, "replace", false, "Stop a running monitor and start in its place (e.g. after upgrading)")
+	fs.Parse(args)
+
+	if *headless {
+		// Exported so the daemon child process inherits it
+		os.Setenv(headlessEnv, "1")
+	}
+
+	if *debugHTTP {
+		// Exported so the daemon child process inherits it
+		os.Setenv(debugHTTPEnv, "1")
+	}
+
+	if *configPath != "" {
+		absPath, err := filepath.Abs(*configPath)
+		if err != nil {
+			log.Fatal("Invalid config path:", err)
+		}
+		// Exported so the daemon child process inherits it
+		os.Setenv(configPathEnv, absPath)
+	}
+
+	return fs.Args()
+}
+
+// info prints an informational line unless --quiet is set. Errors and
+// interactive prompts bypass it.
+func info(a ...any) {
+	if !quietMode {
+		fmt.Println(a...)
+	}
+}
+
+// infof is the Printf form of info
+func infof(format string, a ...any) {
+	if !quietMode {
+		fmt.Printf(format, a...)
+	}
+}
+
+func printUsage() {
+	fmt.Println("Claude Monitor Lite - Menu bar monitor for Claude usage")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  claude-monitor-lite [--config FILE] [--quiet] [--debug-http] [--headless] [--ignore-preflight] [--replace] [command]")
+	fmt.Println()
+	fmt.Println("  claude-monitor-lite           Auto-start (login if needed, show status if running)")
+	fmt.Println("  claude-monitor-lite login     Log in without starting [--stdin to read the key from a pipe]")
+	fmt.Println("  claude-monitor-lite run       Run in the foreground (for brew services, launchd, systemd)")
+	fmt.Println("  claude-monitor-lite stop      Stop the monitor")
+	fmt.Println("  claude-monitor-lite logout    Clear session and stop monitor [--purge to remove all data]")
+	fmt.Println("  claude-monitor-lite pause     Pause polling (keeps the monitor running)")
+	fmt.Println("  claude-monitor-lite resume    Resume polling")
+	fmt.Println("  claude-monitor-lite export    Export usage history as CSV [--since YYYY-MM-DD] [--output FILE]")
+	fmt.Println("  claude-monitor-lite diagnose  Check menu bar availability and daemon health [--output FILE for a bug report bundle]")
+	fmt.Println("  claude-monitor-lite update-check  Check GitHub for a newer release")
+	fmt.Println("  claude-monitor-lite raw       Print the raw usage API response (for bug reports)")
+	fmt.Println("  claude-monitor-lite fetch     Fetch and print usage once, without the monitor [--line] [--no-color] [--format FMT] [--session-key-file FILE] [--org-id ID]")
+	fmt.Println("  claude-monitor-lite status    Show whether the monitor is running and current usage [--format text|json|csv|TEMPLATE]")
+	fmt.Println("  claude-monitor-lite dashboard Full-screen usage dashboard with live countdowns [--interval 30s]")
+	fmt.Println("  claude-monitor-lite org       Show which organization is being monitored (alias: whoami)")
+	fmt.Println("  claude-monitor-lite help      Show this help")
 	fmt.Println()
+	fmt.Println("First time? Just run: claude-monitor-lite")
+}
+
+// offerConfigRestore offers to replace an empty or corrupt config file with
+// its newest backup, before the broken file sends the user through login.
+// Without a terminal to answer, or under --quiet, it only points at the backup.
+func offerConfigRestore() {
+	path := GetConfigPath()
+	if checkConfigFile(path) == nil {
+		return
+	}
+	backup := latestConfigBackup(path)
+	if backup == "" {
+		return
+	}
+	if stdin, err := os.Stdin.Stat(); quietMode || err != nil || stdin.Mode()&os.ModeCharDevice == 0 {
+		fmt.Fprintf(os.Stderr, "Warning: config file is unreadable; a backup is at %s\n", backup)
+		return
+	}
+
+	fmt.Printf("Restore the config from %s? [y/N] ", backup)
+	answer, err := readLine(stdinScanner)
+	if err != nil || !strings.EqualFold(strings.TrimSpace(answer), "y") {
+		info()
+		return
+	}
+	if err := RestoreConfigBackup(backup); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to restore config: %v\n", err)
+		os.Exit(exitError)
+	}
+	setAppConfig(LoadConfig())
+	info("✓ Config restored.")
+	info()
+}
+
+func handleAutoStart() {
+	offerConfigRestore()
+
+	// Check authentication first
+	_, err := LoadAuthSession()
+	if err != nil {
+		// Not logged in - run login flow
+		info("⚠️  Not authenticated")
+		info()
+		_, err = handleLoginFlow()
+		if err != nil {
+			os.Exit(exitCodeFor(err))
+		}
+	}
+
+	// Check if already running
+	if isRunning() && !replaceRunning {
+		// Already running - show status
+		handleStatusDisplay(formatText)
+		return
+	}
+
+	// Not running - show current usage then start it
+	session, err := LoadAuthSession()
+	if err == nil {
+		client := createClientFromSession(session)
+		if limits, err := client.GetUsageLimits(); err == nil {
+			displayUsageStats(limits)
+		}
+	}
+
+	info("⚙️  Starting Claude Monitor Lite...")
+	info()
+	handleStart()
+}
+
+func handleLoginFlow() (*AuthSession, error) {
+	session, err := LoginWithBrowser()
+	var client *claude.ClaudeUsageClient
+	for attempt := 1; ; attempt++ {
+		if err == nil {
+			// Test the session and fetch organization ID
+			client = createClientFromSession(session)
+			if err = client.TestSession(); err == nil {
+				break
+			}
+			if !isAuthError(err) {
+				// Keep the key: it may well be fine once claude.ai is reachable
+				if saveErr := SaveAuthSession(session); saveErr != nil {
+					fmt.Fprintf(os.Stderr, "Failed to save session: %v\n", saveErr)
+				}
+				return nil, reportValidationFailure(err)
+			}
+			if attempt == loginAttempts {
+				return nil, reportValidationFailure(err)
+			}
+		} else if !errors.Is(err, errNoSessionKey) || attempt == loginAttempts {
+			fmt.Fprintf(os.Stderr, "Login failed: %v\n", err)
+			return nil, fmt.Errorf("%w: %w", errLoginIncomplete, err)
+		}
+
+		// An empty or mistyped paste: ask again rather than starting over
+		fmt.Fprintf(os.Stderr, "That didn't work: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Please paste the key again (attempt %d of %d).\n\n", attempt+1, loginAttempts)
+		session, err = promptSessionKey()
+	}
+
+	// Save the key with its organization ID
+	session.OrganizationID = client.OrganizationID()
+	if err := SaveAuthSession(session); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save session: %v\n", err)
+		return nil, fmt.Errorf("%w: %w", errLoginIncomplete, err)
+	}
+
+	refreshAccountProfile(client)
+	if account := cachedAccount(); account != "" {
+		infof("✓ Session validated successfully! Logged in as %s\n", account)
+	} else {
+		info("✓ Session validated successfully!")
+	}
+	info()
 
 	// Fetch and display current usage
 	if limits, err := client.GetUsageLimits(); err != nil {
-		fmt.Printf("Note: Could not fetch usage data: %v\n", err)
-		fmt.Println()
+		infof("Note: Could not fetch usage data: %v\n", err)
+		info()
 	} else {
 		displayUsageStats(limits)
 	}
@@ -310,194 +1268,628 @@ func handleLoginFlow() (*AuthSession, error) {
 	return session, nil
 }
 
-func handleStatusDisplay() {
-	data, _ := os.ReadFile(pidFile)
-	pid, _ := strconv.Atoi(string(data))
-	fmt.Printf("✓ Already running (PID: %d)\n", pid)
-	fmt.Println()
+func handleLogin(args []string) {
+	fs := flag.NewFlagSet("login", flag.ExitOnError)
+	fromStdin := fs.Bool("stdin", false, "Read the session key from stdin (no browser or prompts)")
+	fs.Parse(args)
+
+	if !*fromStdin {
+		if _, err := handleLoginFlow(); err != nil {
+			os.Exit(exitCodeFor(err))
+		}
+		return
+	}
+
+	sessionKey, err := readSessionKey(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Login failed: %v\n", err)
+		os.Exit(exitNotAuthenticated)
+	}
+
+	// Validate before saving so a bad key never replaces a working one
+	session := &AuthSession{SessionKey: sessionKey}
+	client := createClientFromSession(session)
+	if err := client.TestSession(); err != nil {
+		fmt.Fprintf(os.Stderr, "Session validation failed: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	session.OrganizationID = client.OrganizationID()
+	if err := SaveAuthSession(session); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save session: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	refreshAccountProfile(client)
+	info("✓ Session validated and saved.")
+}
+
+// handleStatus shows the monitor's status and current usage whether or not
+// it's running, optionally in a machine-readable --format
+func handleStatus(args []string) {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	format := addFormatFlag(fs)
+	fs.Parse(args)
+
+	if err := checkUsageFormat(*format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	handleStatusDisplay(*format)
+}
+
+func handleStatusDisplay(format string) {
+	// Other formats print only the usage, for scripts
+	if format == formatText {
+		if !isRunning() {
+			info("Not running. Run 'claude-monitor-lite' to start it.")
+		} else if pid, err := readPID(); err == nil {
+			infof("✓ Already running (PID: %d)\n", pid)
+		} else {
+			info("✓ Already running")
+		}
+		info()
+	}
 
 	// Load session
 	session, err := LoadAuthSession()
 	if err != nil {
-		fmt.Println("❌ Not authenticated. Run 'claude-monitor-lite logout' then restart.")
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, "❌ Not authenticated. Run 'claude-monitor-lite logout' then restart.")
+		os.Exit(exitNotAuthenticated)
 	}
 
 	client := createClientFromSession(session)
 	limits, err := client.GetUsageLimits()
 	if err != nil {
-		fmt.Printf("Error loading usage data: %v\n", err)
-		fmt.Println("Try running 'claude-monitor-lite logout' then restart.")
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error loading usage data: %v\n", err)
+		if errors.Is(err, claude.ErrBlocked) {
+			fmt.Fprintln(os.Stderr, "claude.ai returned a Cloudflare challenge. Open https://claude.ai in your browser, then try again.")
+		} else {
+			fmt.Fprintln(os.Stderr, "Try running 'claude-monitor-lite logout' then restart.")
+		}
+		os.Exit(exitCodeFor(err))
 	}
 
+	if format != formatText {
+		printUsageFormat(limits, format)
+		return
+	}
 	displayUsageStats(limits)
 
 	// Show which indicator is displayed in menu bar
-	indicatorNames := map[string]string{
-		"currentSession": "5-Hour Session",
-		"weeklyAll":      "Weekly (All)",
-		"weeklyOpus":     "Weekly (Opus)",
+	config := currentConfig()
+	indicatorName := limitKinds[0].Label
+	if kind, ok := findLimitKind(selectedLimitType(config.MenuBarIndicator)); ok {
+		indicatorName = kind.Label
 	}
 
-	indicatorName := indicatorNames[appConfig.MenuBarIndicator]
-	if indicatorName == "" {
-		indicatorName = "5-Hour Session"
-	}
-
-	limit := getSelectedLimit(limits, appConfig.MenuBarIndicator)
+	limit := getSelectedLimit(limits, config.MenuBarIndicator)
 	utilization := 0.0
 	if limit != nil {
 		utilization = limit.Utilization
 	}
 
-	fmt.Printf("Menu Bar Shows:  %s (%s %d%%)\n", indicatorName, getColorIndicator(utilization), roundUtilization(utilization))
+	infof("Menu Bar Shows:  %s (%s %s)\n", indicatorName, getColorIndicator(utilization, selectedLimitType(config.MenuBarIndicator)), formatPercent(utilization))
+
+	if config.Paused {
+		info("Updates paused. Run 'claude-monitor-lite resume' to continue.")
+	}
+	if days, aging := agingSessionDays(session.SavedAt, time.Now()); aging {
+		infof("Session saved %d days ago and may expire soon. Run 'claude-monitor-lite login' to refresh it.\n", days)
+	}
+}
+
+// handleRaw prints the usage endpoint's JSON as returned, pretty-printed
+func handleRaw() {
+	session, err := LoadAuthSession()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Not authenticated. Run 'claude-monitor-lite login' first.")
+		os.Exit(exitNotAuthenticated)
+	}
+
+	body, err := createClientFromSession(session).GetRawUsage()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading usage data: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	fmt.Fprintln(os.Stderr, "Note: this may contain account identifiers. Review it before sharing.")
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, body, "", "  "); err != nil {
+		// Not JSON after all; show it exactly as received
+		os.Stdout.Write(body)
+		fmt.Println()
+		return
+	}
+	fmt.Println(pretty.String())
+}
+
+// handleFetch fetches usage once and prints it, for scripts and status bars
+// that poll on their own schedule
+func handleFetch(args []string) {
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	line := fs.Bool("line", false, "Print one compact line, e.g. \"5h:32% 7d:55% opus:91%\"")
+	noColor := fs.Bool("no-color", false, "Leave out the usage level markers in --line output")
+	format := addFormatFlag(fs)
+	sessionArgs := addSessionFlags(fs)
+	fs.Parse(args)
+
+	if err := checkUsageFormat(*format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if *line && *format != formatText {
+		fmt.Fprintln(os.Stderr, "Error: --line and --format can't be combined")
+		os.Exit(exitUsage)
+	}
+
+	session, err := sessionArgs.session()
+	if err != nil {
+		if *sessionArgs.key == "" && *sessionArgs.keyFile == "" {
+			fmt.Fprintln(os.Stderr, "❌ Not authenticated. Run 'claude-monitor-lite login' first, or pass --session-key-file.")
+			os.Exit(exitNotAuthenticated)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	limits, err := createClientFromSession(session).GetUsageLimits()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading usage data: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+
+	if *line {
+		// No trailing newline, so it embeds cleanly
+		fmt.Print(formatStatusLine(limits, !*noColor))
+		return
+	}
+	printUsageFormat(limits, *format)
+}
+
+// Helper function to add the --format flag shared by fetch and status
+func addFormatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", formatText, "Output format: text, json, csv, or a Go template like '{{.FiveHour.Utilization}}'")
+}
+
+// Helper function to print usage in a --format, exiting on a template error.
+// Only the text format is informational output that --quiet suppresses.
+func printUsageFormat(limits *claude.UsageLimits, format string) {
+	if format == formatText {
+		displayUsageStats(limits)
+		return
+	}
+	if err := formatUsage(os.Stdout, limits, format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+}
+
+// Helper function to format every available limit on one line, e.g.
+// "5h:32% 7d:55% opus:91%", optionally prefixed with usage level markers
+func formatStatusLine(limits *claude.UsageLimits, withIndicators bool) string {
+	var parts []string
+	for _, kind := range displayedLimitKinds() {
+		limit := kind.Get(limits)
+		if limit == nil {
+			continue
+		}
+
+		part := fmt.Sprintf("%s:%s%%", kind.ShortLabel, formatDisplayPercentNumber(limit.Utilization))
+		if withIndicators {
+			part = getColorIndicator(limit.Utilization, kind.Key) + part
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+// handleOrg prints the organization the monitor reads usage from, resolving
+// and saving its ID if none is stored yet
+func handleOrg() {
+	session, err := LoadAuthSession()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Not authenticated. Run 'claude-monitor-lite login' first.")
+		os.Exit(exitNotAuthenticated)
+	}
+
+	source := "saved in config"
+	if session.OrganizationID == "" {
+		source = "resolved from claude.ai"
+	}
+
+	org, err := createClientFromSession(session).GetOrganization()
+	if err != nil {
+		if session.OrganizationID == "" {
+			fmt.Fprintf(os.Stderr, "Error resolving organization: %v\n", err)
+			os.Exit(exitCodeFor(err))
+		}
+		// The stored ID is still worth showing without a name
+		fmt.Fprintf(os.Stderr, "Warning: could not look up the organization name: %v\n", err)
+		org = &claude.Organization{ID: session.OrganizationID}
+	}
+	if session.OrganizationID == "" {
+		saveOrganizationID(org.ID)
+	}
+
+	name := org.Name
+	if name == "" {
+		name = "(unknown)"
+	}
+	fmt.Printf("Organization: %s\n", name)
+	fmt.Printf("ID:           %s (%s)\n", org.ID, source)
+	if org.Plan != "" {
+		fmt.Printf("Plan:         %s\n", org.Plan)
+	}
+	if account := cachedAccount(); account != "" {
+		fmt.Printf("Account:      %s\n", account)
+	}
 }
 
 func handleStart() {
 	if os.Getenv("CLAUDE_MONITOR_DAEMON") != "1" {
 		if isRunning() {
-			fmt.Println("Claude Monitor Lite is already running.")
-			fmt.Println("Use 'claude-monitor-lite stop' to stop it first.")
-			os.Exit(1)
+			if !replaceRunning {
+				fmt.Fprintln(os.Stderr, "Claude Monitor Lite is already running.")
+				fmt.Fprintln(os.Stderr, "Use 'claude-monitor-lite stop' to stop it first, or --replace.")
+				os.Exit(exitAlreadyRunning)
+			}
+			replaceMonitor()
 		}
+		preflightCheck()
 	}
 
 	daemonize()
+	setupDaemonLog()
+	runMonitor()
+}
+
+// handleRun runs the monitor in the foreground for process supervisors
+// (brew services, launchd, systemd): no fork, and logs go to stderr
+func handleRun() {
+	if isRunning() {
+		if !replaceRunning {
+			fmt.Fprintln(os.Stderr, "Claude Monitor Lite is already running.")
+			os.Exit(exitAlreadyRunning)
+		}
+		replaceMonitor()
+	}
+	runMonitor()
+}
 
+// runMonitor runs the monitor in this process until it's quit or signalled
+func runMonitor() {
 	if err := createPIDFile(); err != nil {
 		log.Fatal("Failed to create PID file:", err)
 	}
+	pruneHistory(time.Now())
+
+	// Created before the signal handler so a signal at any point cancels it
+	appCtx, appCancel = context.WithCancel(context.Background())
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sigChan
 		cleanup()
-		os.Exit(0)
+		os.Exit(exitOK)
 	}()
 
+	signal.Notify(reloadChan, syscall.SIGHUP)
+
+	subscribeUsageHandlers()
+	if headlessMode {
+		runHeadless()
+		// Returns once cleanup cancels appCtx; wait for it to finish
+		cleanup()
+		return
+	}
+
+	go watchSystrayReady()
 	systray.Run(onReady, onExit)
 }
 
+// preflightCheck validates the session before forking, so a dead session or
+// unreachable claude.ai doesn't leave a daemon that only ever shows errors.
+// With --ignore-preflight a failure is only a warning.
+func preflightCheck() {
+	session, err := LoadAuthSession()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Not authenticated. Run 'claude-monitor-lite login' first.")
+		os.Exit(exitNotAuthenticated)
+	}
+
+	client := createClientFromSession(session)
+	client.SetTimeout(preflightTimeout)
+	err = client.TestSession()
+	if err == nil {
+		return
+	}
+
+	if errors.Is(err, claude.ErrSessionExpired) {
+		fmt.Fprintln(os.Stderr, "❌ Session expired. Run 'claude-monitor-lite logout' then restart to log in again.")
+	} else {
+		fmt.Fprintf(os.Stderr, "❌ Could not reach claude.ai: %v\n", err)
+	}
+	if ignorePreflight {
+		fmt.Fprintln(os.Stderr, "Starting anyway (--ignore-preflight).")
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Not starting. Use --ignore-preflight to start anyway.")
+	os.Exit(exitCodeFor(err))
+}
+
 func handleStop() {
 	if !isRunning() {
-		fmt.Println("Claude Monitor Lite is not running.")
-		os.Exit(0)
+		info("Claude Monitor Lite is not running.")
+		os.Exit(exitNotRunning)
 	}
 
-	data, err := os.ReadFile(pidFile)
+	pid, err := stopMonitor()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to read PID file: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Failed to stop monitor: %v\n", err)
+		os.Exit(exitError)
 	}
 
-	pid, err := strconv.Atoi(string(data))
+	infof("Claude Monitor Lite (PID: %d) stopped.\n", pid)
+}
+
+// replaceMonitor stops the running monitor for --replace, exiting if it
+// won't stop rather than run two monitors side by side
+func replaceMonitor() {
+	info("Stopping the running monitor...")
+	pid, err := stopMonitor()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Invalid PID: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Failed to replace monitor: %v\n", err)
+		os.Exit(exitError)
 	}
+	infof("Stopped previous monitor (PID: %d).\n", pid)
+}
 
-	process, err := os.FindProcess(pid)
+// stopMonitor sends SIGTERM to the running monitor and waits for it to exit.
+// Once it has, its cleanup can no longer remove a PID file written after it.
+func stopMonitor() (int, error) {
+	pid, err := signalMonitor(syscall.SIGTERM)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to find process: %v\n", err)
-		os.Exit(1)
+		return 0, err
 	}
 
-	if err := process.Signal(syscall.SIGTERM); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to stop process: %v\n", err)
-		os.Exit(1)
+	deadline := time.Now().Add(stopTimeout)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			return pid, fmt.Errorf("process %d still running after %s", pid, stopTimeout)
+		}
+		time.Sleep(pidPollInterval)
 	}
 
-	fmt.Printf("Claude Monitor Lite (PID: %d) stopped.\n", pid)
-	time.Sleep(pidCheckTimeout)
-	if err := os.Remove(pidFile); err != nil && !os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "Warning: Failed to remove PID file: %v\n", err)
+	// Normally its cleanup removed it. A replacement started meanwhile has
+	// already written its own, which must stay.
+	if current, err := readPID(); err == nil && current == pid {
+		if err := os.Remove(pidFile); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to remove PID file: %v\n", err)
+		}
 	}
+	return pid, nil
 }
 
-func handleLogout() {
+// Helper function to check whether a process with pid exists
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+func handleLogout(args []string) {
+	fs := flag.NewFlagSet("logout", flag.ExitOnError)
+	purge := fs.Bool("purge", false, "Remove all config, preferences and history, not just the session")
+	fs.Parse(args)
+
 	// Stop daemon if running
 	if isRunning() {
-		fmt.Println("Stopping monitor...")
-		data, err := os.ReadFile(pidFile)
-		if err == nil {
-			pid, err := strconv.Atoi(string(data))
-			if err == nil {
-				process, err := os.FindProcess(pid)
-				if err == nil {
-					process.Signal(syscall.SIGTERM)
-					time.Sleep(pidCheckTimeout)
-				}
-			}
+		info("Stopping monitor...")
+		if _, err := stopMonitor(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to stop monitor: %v\n", err)
 		}
-		if err := os.Remove(pidFile); err != nil && !os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to remove PID file: %v\n", err)
+	}
+
+	var backupPath string
+	if *purge {
+		// Keep the preferences in case the purge was accidental. The session
+		// is left out: logging out shouldn't leave it on disk.
+		var err error
+		if backupPath, err = BackupPreferences(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to back up config: %v\n", err)
+			os.Exit(exitError)
 		}
+
+		if err := PurgeConfig(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to remove config: %v\n", err)
+			os.Exit(exitError)
+		}
+		if err := os.Remove(historyFile); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to remove history: %v\n", err)
+		}
+		if err := os.Remove(budgetFile); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to remove request count: %v\n", err)
+		}
+		info("✓ Logged out! All config and session data removed.")
+	} else {
+		if err := ClearAuthSession(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to clear session: %v\n", err)
+			os.Exit(exitError)
+		}
+		info("✓ Logged out! Preferences were kept (use 'logout --purge' to remove everything).")
+	}
+
+	if backupPath != "" {
+		infof("Previous preferences backed up to %s\n", backupPath)
+	}
+}
+
+func handlePause(paused bool) {
+	if err := UpdateConfig(func(config *Config) { config.Paused = paused }); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save config: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	// Ask the running daemon to pick up the new state
+	if isRunning() {
+		if _, err := signalMonitor(syscall.SIGHUP); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to signal monitor: %v\n", err)
+		}
+	}
+
+	if paused {
+		info("✓ Updates paused.")
+	} else {
+		info("✓ Updates resumed.")
+	}
+}
+
+func isRunning() bool {
+	return pidFileAlive(pidFile)
+}
+
+// pidFileAlive reports whether path names a live monitor process,
+// removing the file if it is stale
+func pidFileAlive(path string) bool {
+	pid, executable, err := readPIDFile(path)
+	if err != nil {
+		return false
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		// Process doesn't exist, clean up stale PID file
+		os.Remove(path)
+		return false
+	}
+
+	// Send signal 0 to check if process is alive
+	err = process.Signal(syscall.Signal(0))
+	if err != nil {
+		// Process is dead, clean up stale PID file
+		os.Remove(path)
+		return false
+	}
+
+	// A live PID may have been reused by an unrelated process (e.g. after a reboot)
+	if executable != "" && !isSameExecutable(pid, executable) {
+		os.Remove(path)
+		return false
+	}
+
+	return true
+}
+
+// createPIDFile writes our PID and executable path, one per line
+func createPIDFile() error {
+	if pidFile == "" {
+		return errors.New("PID file path not set")
 	}
 
-	// Clear session and config
-	if err := ClearAuthSession(); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to clear session: %v\n", err)
-		os.Exit(1)
+	content := strconv.Itoa(os.Getpid()) + "\n"
+	if executable, err := os.Executable(); err == nil {
+		content += executable + "\n"
 	}
-	fmt.Println("✓ Logged out! All config and session data removed.")
+	// Written atomically so a crash can't leave a truncated PID behind
+	return writeFileAtomic(pidFile, pidFilePermissions, func(w io.Writer) error {
+		_, err := io.WriteString(w, content)
+		return err
+	})
 }
 
-func isRunning() bool {
-	data, err := os.ReadFile(pidFile)
+// readPID returns the PID of the monitor recorded in the PID file
+func readPID() (int, error) {
+	pid, _, err := readPIDFile(pidFile)
+	return pid, err
+}
+
+// readPIDFile reads and parses the PID file at path. A file that doesn't
+// parse (e.g. left empty by an older version killed mid-write) is removed,
+// so every caller sees the same "not running" state afterwards.
+func readPIDFile(path string) (pid int, executable string, err error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return false
+		return 0, "", err
 	}
 
-	pid, err := strconv.Atoi(string(data))
+	pid, executable, err = parsePIDFile(data)
+	if err == nil && pid <= 0 {
+		err = fmt.Errorf("PID %d out of range", pid)
+	}
 	if err != nil {
-		// Invalid PID file, clean it up
-		os.Remove(pidFile)
-		return false
+		os.Remove(path)
+		return 0, "", fmt.Errorf("removed invalid PID file %s: %w", path, err)
 	}
+	return pid, executable, nil
+}
 
-	process, err := os.FindProcess(pid)
+// signalMonitor sends sig to the running monitor and returns its PID
+func signalMonitor(sig os.Signal) (int, error) {
+	pid, err := readPID()
 	if err != nil {
-		// Process doesn't exist, clean up stale PID file
-		os.Remove(pidFile)
-		return false
+		return 0, err
 	}
 
-	// Send signal 0 to check if process is alive
-	err = process.Signal(syscall.Signal(0))
+	process, err := os.FindProcess(pid)
 	if err != nil {
-		// Process is dead, clean up stale PID file
-		os.Remove(pidFile)
-		return false
+		return 0, fmt.Errorf("failed to find process %d: %w", pid, err)
 	}
-
-	return true
+	if err := process.Signal(sig); err != nil {
+		return 0, fmt.Errorf("failed to signal process %d: %w", pid, err)
+	}
+	return pid, nil
 }
 
-func createPIDFile() error {
-	return os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())), pidFilePermissions)
+// parsePIDFile returns the PID and, if recorded, the executable path.
+// Older PID files contain only the PID.
+func parsePIDFile(data []byte) (pid int, executable string, err error) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	pid, err = strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return 0, "", err
+	}
+	if len(lines) > 1 {
+		executable = strings.TrimSpace(lines[1])
+	}
+	return pid, executable, nil
 }
 
+// cleanup stops the monitor's background work and removes its files. A
+// signal can arrive while Quit is already shutting down, so only the first
+// call does anything; later ones wait for it to finish.
 func cleanup() {
-	if pidFile != "" {
-		if err := os.Remove(pidFile); err != nil && !os.IsNotExist(err) {
-			log.Printf("Warning: Failed to remove PID file: %v\n", err)
+	cleanupOnce.Do(func() {
+		if appCancel != nil {
+			appCancel()
 		}
-	}
+		waitForFetches(shutdownTimeout)
+		stopSocketServer()
+		stopFifo()
+		// A replacement may have written its own PID file by now; leave that one
+		if pid, err := readPID(); err == nil && pid == os.Getpid() {
+			if err := os.Remove(pidFile); err != nil && !os.IsNotExist(err) {
+				log.Printf("Warning: Failed to remove PID file: %v\n", err)
+			}
+		}
+	})
 }
 
 func onReady() {
-	// Create context for graceful shutdown
-	appCtx, appCancel = context.WithCancel(context.Background())
+	close(systrayReady)
 
-	systray.SetTitle("⚪ Loading...")
+	initMenuBarIcons()
+	setStatusTitle(iconLoading, "Loading...")
 	systray.SetTooltip("Claude Monitor Lite")
 
 	// Check authentication
 	session, err := LoadAuthSession()
 	if err != nil {
-		systray.SetTitle("⚪ Not logged in")
+		setStatusTitle(iconAuthExpired, "Not logged in")
 		mLogin := systray.AddMenuItem("⚠️  Please login first", "Login required")
 		mLogin.Disable()
 		systray.AddSeparator()
@@ -512,124 +1904,782 @@ func onReady() {
 		return
 	}
 
-	claudeClient = createClientFromSession(session)
+	client := createClientFromSession(session)
+	claudeClient.Store(client)
+
+	mAccount = systray.AddMenuItem("", "Logged-in account")
+	mAccount.Disable()
+	mPlan = systray.AddMenuItem("", "Subscription plan the limits belong to")
+	mPlan.Disable()
+	updateAccountMenu()
+	mSessionAge = systray.AddMenuItem("", "Session keys expire; log in again before this one does")
+	mSessionAge.Disable()
+	updateSessionAgeMenu()
+	config := currentConfig()
+	// Looked up once per session: some accounts have no name or plan listed
+	if config.AccountCheckedAt == nil {
+		go func() {
+			refreshAccountProfile(client)
+			updateAccountMenu()
+		}()
+	}
 
-	mCurrentSession = systray.AddMenuItem("5-Hour Session: --", "Click to show in menu bar")
-	mWeeklyAll = systray.AddMenuItem("Weekly (All): --", "Click to show in menu bar")
-	mWeeklyOpus = systray.AddMenuItem("Weekly (Opus): --", "Click to show in menu bar")
+	addLimitMenuItems()
+	addResetTimesMenu()
+	addRecentMenu()
 	systray.AddSeparator()
 
 	mRefresh = systray.AddMenuItem("Refresh Now", "Refresh usage data")
+	mRedetectOrg := systray.AddMenuItem("Re-detect Organization", "Look up the organization again, e.g. after switching or joining one")
+	mPause = systray.AddMenuItem("Pause Updates", "Stop polling until resumed")
+	mShowRemaining = systray.AddMenuItemCheckbox("Show Remaining", "Show percent left instead of percent used", config.ShowRemaining)
+	addSnoozeMenu()
+	mOpenUsage := systray.AddMenuItem("Open Claude Usage", "Open the usage page on claude.ai")
+	mCopyUsage := systray.AddMenuItem("Copy Usage Summary", "Copy current usage to the clipboard")
+	mUpdate = systray.AddMenuItem("Update Available", "Open the release page")
+	mUpdate.Hide()
+	go checkForUpdate()
 	systray.AddSeparator()
 
 	mQuit := systray.AddMenuItem("Quit", "Quit the application")
 
 	updateMenuCheckmarks()
-	go updateStats()
+	applyPaused(config.Paused)
+	applySnooze(config.SnoozeUntil)
+	menuBuilt.open()
+	fetchWG.Go(warmUpStats)
+
+	if config.EnableSocket {
+		startSocketServer()
+	}
+	if config.EnableFifo {
+		startFifo()
+	}
 
 	go func() {
-		ticker := time.NewTicker(refreshInterval)
+		ticker := time.NewTicker(nextRefreshInterval())
 		defer ticker.Stop()
+		woke := watchForWake(appCtx)
 
 		for {
 			select {
 			case <-appCtx.Done():
 				return
 			case <-ticker.C:
-				go updateStats()
+				startUpdate()
+				ticker.Reset(nextRefreshInterval())
+			case <-woke:
+				handleWake()
+				ticker.Reset(nextRefreshInterval())
 			case <-mQuit.ClickedCh:
 				appCancel()
 				systray.Quit()
 				return
 			case <-mRefresh.ClickedCh:
-				go updateStats()
-			case <-mCurrentSession.ClickedCh:
-				appConfig.MenuBarIndicator = "currentSession"
-				updateMenuCheckmarks()
-				limitsMutex.RLock()
-				cached := lastLimits
-				limitsMutex.RUnlock()
-				if cached != nil {
-					updateMenuBarDisplay(cached)
+				startManualUpdate()
+			case <-mRedetectOrg.ClickedCh:
+				go redetectOrganization()
+			case <-mOpenUsage.ClickedCh:
+				if err := openBrowser(usageDashboardURL); err != nil {
+					log.Printf("Warning: %v\n", err)
 				}
-				go SaveConfigPreservingSession("currentSession")
-			case <-mWeeklyAll.ClickedCh:
-				appConfig.MenuBarIndicator = "weeklyAll"
-				updateMenuCheckmarks()
+			case <-mCopyUsage.ClickedCh:
 				limitsMutex.RLock()
 				cached := lastLimits
 				limitsMutex.RUnlock()
 				if cached != nil {
-					updateMenuBarDisplay(cached)
+					if err := copyToClipboard(formatTooltip(cached)); err != nil {
+						log.Printf("Warning: %v\n", err)
+					}
 				}
-				go SaveConfigPreservingSession("weeklyAll")
-			case <-mWeeklyOpus.ClickedCh:
-				appConfig.MenuBarIndicator = "weeklyOpus"
-				updateMenuCheckmarks()
-				limitsMutex.RLock()
-				cached := lastLimits
-				limitsMutex.RUnlock()
-				if cached != nil {
-					updateMenuBarDisplay(cached)
+			case <-mUpdate.ClickedCh:
+				if url := updateURL.Load(); url != nil {
+					if err := openBrowser(*url); err != nil {
+						log.Printf("Warning: %v\n", err)
+					}
+				}
+			case <-mPause.ClickedCh:
+				paused := updateAppConfig(func(config *Config) { config.Paused = !config.Paused }).Paused
+				applyPaused(paused)
+				persistConfig(func(file, current *Config) { file.Paused = current.Paused })
+			case <-mShowRemaining.ClickedCh:
+				updateAppConfig(func(config *Config) { config.ShowRemaining = !config.ShowRemaining })
+				updateShowRemainingCheck()
+				redrawFromCache()
+				persistConfig(func(file, current *Config) { file.ShowRemaining = current.ShowRemaining })
+			case d := <-snoozeChan:
+				var until *time.Time
+				if d > 0 {
+					t := time.Now().Add(d)
+					until = &t
 				}
-				go SaveConfigPreservingSession("weeklyOpus")
+				updateAppConfig(func(config *Config) { config.SnoozeUntil = until })
+				applySnooze(until)
+				persistConfig(func(file, current *Config) { file.SnoozeUntil = current.SnoozeUntil })
+			case <-reloadChan:
+				reloadConfig()
+			case indicator := <-indicatorChan:
+				updateAppConfig(func(config *Config) { config.MenuBarIndicator = indicator })
+				updateMenuCheckmarks()
+				redrawFromCache()
+				persistConfig(func(file, current *Config) { file.MenuBarIndicator = current.MenuBarIndicator })
 			}
 		}
 	}()
 }
 
+// addLimitMenuItems adds a usage item per limit, after a hidden error item.
+// Clicks are forwarded to the refresh loop to select that limit for the
+// menu bar.
+func addLimitMenuItems() {
+	mFetchError = systray.AddMenuItem("", "The last update failed")
+	mFetchError.Disable()
+	mFetchError.Hide()
+
+	menuKinds = displayedLimitKinds()
+	limitItemKinds = slices.Clone(menuKinds)
+	for _, kind := range limitKinds {
+		if !slices.ContainsFunc(limitItemKinds, func(k limitKind) bool { return k.Key == kind.Key }) {
+			limitItemKinds = append(limitItemKinds, kind)
+		}
+	}
+	for _, kind := range limitItemKinds {
+		item := systray.AddMenuItem(kind.Label+": --", "Click to show in menu bar")
+		go func() {
+			for range item.ClickedCh {
+				indicatorChan <- kind.Indicator
+			}
+		}()
+		mLimitItems = append(mLimitItems, item)
+	}
+}
+
+// updateMenuCheckmarks checks the menu bar's limit, showing its item even
+// if displayLimits leaves it out
 func updateMenuCheckmarks() {
-	mCurrentSession.Uncheck()
-	mWeeklyAll.Uncheck()
-	mWeeklyOpus.Uncheck()
+	selected := selectedLimitType(currentConfig().MenuBarIndicator)
+	for i, kind := range limitItemKinds {
+		item := mLimitItems[i]
+		if kind.Key == selected {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+		if kind.Key == selected || i < len(menuKinds) {
+			item.Show()
+		} else {
+			item.Hide()
+		}
+	}
+}
 
-	switch appConfig.MenuBarIndicator {
-	case "currentSession":
-		mCurrentSession.Check()
-	case "weeklyAll":
-		mWeeklyAll.Check()
-	case "weeklyOpus":
-		mWeeklyOpus.Check()
+// updateShowRemainingCheck syncs the used/remaining toggle with the config
+func updateShowRemainingCheck() {
+	if currentConfig().ShowRemaining {
+		mShowRemaining.Check()
+	} else {
+		mShowRemaining.Uncheck()
+	}
+}
+
+// formatAccount returns "Name (email)", whichever parts are known
+func formatAccount(name, email string) string {
+	switch {
+	case name != "" && email != "":
+		return fmt.Sprintf("%s (%s)", name, email)
+	case name != "":
+		return name
 	default:
-		mCurrentSession.Check()
+		return email
+	}
+}
+
+// Helper function to format the account cached in the config
+func cachedAccount() string {
+	config := currentConfig()
+	return formatAccount(config.AccountName, config.AccountEmail)
+}
+
+// updateAccountMenu shows the cached account and plan in the menu header
+func updateAccountMenu() {
+	config := currentConfig()
+	if plan := config.AccountPlan; plan != "" {
+		mPlan.SetTitle("Plan: " + plan)
+		mPlan.Show()
+	} else {
+		mPlan.Hide()
 	}
+
+	account := formatAccount(config.AccountName, config.AccountEmail)
+	if account == "" {
+		mAccount.Hide()
+		return
+	}
+	mAccount.SetTitle(account)
+	mAccount.Show()
 }
 
-func updateStats() {
-	if claudeClient == nil {
-		systray.SetTitle("⚪ Error")
+// updateRefreshMenu warns on Refresh Now once it would go over the daily
+// request budget
+func updateRefreshMenu() {
+	if budgetExhausted(time.Now()) {
+		mRefresh.SetTitle("Refresh Now (over today's request budget)")
 		return
 	}
+	mRefresh.SetTitle("Refresh Now")
+}
 
-	limits, err := claudeClient.GetUsageLimits()
-	if err != nil {
-		systray.SetTitle("⚪ Error")
-		mCurrentSession.SetTitle("Error loading data")
+// updateSessionAgeMenu shows a re-login hint under the account while the
+// session is older than sessionWarnAfterDays
+func updateSessionAgeMenu() {
+	days, aging := agingSessionDays(savedSessionTime(), time.Now())
+	if !aging {
+		mSessionAge.Hide()
+		return
+	}
+	mSessionAge.SetTitle(fmt.Sprintf("%s Session is %d days old - consider logging in again", sessionAgingMarker(), days))
+	mSessionAge.Show()
+}
+
+// refreshAccountProfile fetches the account profile and plan and caches them
+// in the config. Failures are ignored: the header is a nicety, not an error.
+func refreshAccountProfile(client *claude.ClaudeUsageClient) {
+	profile, profileErr := client.GetAccountProfile()
+	plan, planErr := client.GetPlan()
+	if profileErr != nil && planErr != nil {
+		return
+	}
+
+	now := time.Now()
+	setAccount := func(config *Config) {
+		if profileErr == nil {
+			config.AccountName = profile.Name
+			config.AccountEmail = profile.Email
+		}
+		if planErr == nil {
+			config.AccountPlan = plan
+		}
+		// A failed half is retried on the next launch
+		if profileErr == nil && planErr == nil {
+			config.AccountCheckedAt = &now
+		}
+	}
+	updateAppConfig(setAccount)
+	if err := UpdateConfig(setAccount); err != nil {
+		log.Printf("Warning: Failed to save account profile: %v\n", err)
+	}
+}
+
+// persistConfig saves a menu change in the background, logging failures.
+// copyField copies the setting from the in-memory config as it is when the
+// save runs, so saves finishing out of order still leave the latest value.
+func persistConfig(copyField func(file, current *Config)) {
+	go func() {
+		err := UpdateConfig(func(file *Config) { copyField(file, currentConfig()) })
+		if err != nil {
+			log.Printf("Warning: Failed to save config: %v\n", err)
+		}
+	}()
+}
+
+// redrawFromCache re-renders the menu from the last fetched limits, if any
+func redrawFromCache() {
+	limitsMutex.RLock()
+	cached := lastLimits
+	limitsMutex.RUnlock()
+	if cached == nil {
+		return
+	}
+
+	updateUsageMenu(cached)
+	if !isPaused.Load() {
+		updateMenuBarDisplay(cached)
+	}
+}
+
+// reloadConfig re-reads the config file and applies it without a restart
+func reloadConfig() {
+	config := LoadConfig()
+	setAppConfig(config)
+	applyPaused(config.Paused)
+	applySnooze(config.SnoozeUntil)
+	if !headlessMode {
+		updateMenuCheckmarks()
+		updateShowRemainingCheck()
+		updateAccountMenu()
+		updateSessionAgeMenu()
+		updateRecentMenu()
+		redrawFromCache()
+	}
+	log.Println("Config reloaded")
+}
+
+// applyPaused switches polling on or off and updates the menu to match
+func applyPaused(paused bool) {
+	wasPaused := isPaused.Swap(paused)
+	if paused {
+		if !headlessMode {
+			mPause.SetTitle("Resume Updates")
+			setStatusTitle("⏸", "Paused")
+		}
+		return
+	}
+
+	if !headlessMode {
+		mPause.SetTitle("Pause Updates")
+	}
+	if wasPaused {
+		startUpdate()
+	}
+}
+
+// addSnoozeMenu adds the Snooze Notifications submenu. Clicks are forwarded
+// to snoozeChan so the refresh loop owns the config changes.
+func addSnoozeMenu() {
+	mSnooze = systray.AddMenuItem("Snooze Notifications", "Hold usage alerts for a while")
+	for _, d := range snoozeDurations {
+		item := mSnooze.AddSubMenuItem(formatSnoozeDuration(d), "Hold usage alerts for "+formatSnoozeDuration(d))
+		go func() {
+			for range item.ClickedCh {
+				snoozeChan <- d
+			}
+		}()
+	}
+
+	mSnoozeCancel = mSnooze.AddSubMenuItem("Resume Notifications", "End the snooze now")
+	go func() {
+		for range mSnoozeCancel.ClickedCh {
+			snoozeChan <- 0
+		}
+	}()
+}
+
+// formatSnoozeDuration returns e.g. "1 hour" or "4 hours"
+func formatSnoozeDuration(d time.Duration) string {
+	if hours := int(d.Hours()); hours != 1 {
+		return fmt.Sprintf("%d hours", hours)
+	}
+	return "1 hour"
+}
+
+// applySnooze sets the snooze end time and updates the menu to match. An
+// expired or nil time clears the snooze.
+func applySnooze(until *time.Time) {
+	if until == nil {
+		setSnooze(time.Time{})
+	} else {
+		setSnooze(*until)
+	}
+	if !headlessMode {
+		updateSnoozeMenu()
+	}
+}
+
+// updateSnoozeMenu shows when the snooze ends, reverting once it expires
+func updateSnoozeMenu() {
+	if until, ok := snoozedUntil(time.Now()); ok {
+		mSnooze.SetTitle("Notifications Snoozed Until " + until.Format("15:04"))
+		mSnoozeCancel.Show()
+		return
+	}
+	mSnooze.SetTitle("Snooze Notifications")
+	mSnoozeCancel.Hide()
+}
+
+// startUpdate runs updateStats in the background, tracked for shutdown
+func startUpdate() {
+	fetchWG.Go(func() { updateStats(false) })
+}
+
+// startManualUpdate is startUpdate for Refresh Now, which goes ahead even
+// when the daily request budget is used up
+func startManualUpdate() {
+	fetchWG.Go(func() { updateStats(true) })
+}
+
+// waitForFetches blocks until in-flight fetches finish or timeout elapses,
+// so they don't write history or cache files mid-shutdown
+func waitForFetches(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		fetchWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Println("Warning: Shutting down with fetches still in flight")
+	}
+}
+
+// updateStats fetches fresh limits and updates the UI. The error is only
+// used by the startup warm-up; callers on the ticker ignore it. Only forced
+// fetches (Refresh Now and the first one after startup) go over
+// maxDailyRequests.
+func updateStats(force bool) error {
+	if isPaused.Load() {
+		return nil
+	}
+	if !spendRequest(force) {
+		return errBudgetExhausted
+	}
+	if budgetExhausted(time.Now()) {
+		// Redraw even if nothing changed, to show the budget marker
+		drawnMinute.Store(0)
+	}
 
-		// Check if session expired using typed error
-		if errors.Is(err, ErrAuthFailed) {
-			mCurrentSession.SetTitle("Session expired - please login again")
+	streak, err := fetchStats()
+	checkFailureNotification(err, streak)
+	if headlessMode {
+		if err != nil {
+			log.Printf("Warning: Failed to fetch usage: %v\n", err)
 		}
+		return err
+	}
+	if err == nil {
+		return nil
+	}
+	if !menuBuilt.wait(appCtx) {
+		return err
+	}
+
+	drawnMinute.Store(0)
+	limitsMutex.RLock()
+	cached := lastLimits
+	limitsMutex.RUnlock()
+	if cached != nil && withinErrorGrace(err, streak) {
+		// Likely a blip: keep the last values, marked, for another try
+		updateMenuBarDisplay(cached)
+		return err
+	}
+	showFetchError(err)
+	return err
+}
+
+// Helper function to check whether a failed fetch, the streak-th in a row,
+// is still within the errorAfterFailures grace period. An expired session or
+// a block needs action, so it shows at once.
+func withinErrorGrace(err error, streak int) bool {
+	if errors.Is(err, claude.ErrAuthFailed) || errors.Is(err, claude.ErrBlocked) {
+		return false
+	}
+	return streak < currentConfig().ErrorAfterFailures
+}
+
+// redrawUsage updates the menu and menu bar after a fetch
+func redrawUsage(event UsageUpdated) {
+	if !menuBuilt.wait(appCtx) {
+		return
+	}
+
+	// Countdowns only tick over on the minute, so unchanged limits need no
+	// redraw until the next one
+	minute := event.At.Unix() / 60
+	if !event.Changed && drawnMinute.Load() == minute {
 		return
 	}
+	drawnMinute.Store(minute)
+
+	updateUsageMenu(event.Limits)
+	updateRefreshMenu()
+	updateSnoozeMenu()
+	updateSessionAgeMenu()
+	updateMenuBarDisplay(event.Limits)
+	if event.Changed {
+		// recordHistory has already appended this fetch
+		updateRecentMenu()
+	}
+}
+
+// fetchStats fetches fresh limits, caches them and publishes a UsageUpdated
+// event for the menu, history and notifications. Shared with headless mode.
+// Returns the failed fetches in a row, counted before the event goes out so
+// its redraw already sees a recovery.
+func fetchStats() (int, error) {
+	client := claudeClient.Load()
+	if client == nil {
+		err := claude.ErrAuthFailed
+		return fetchFailures.record(err), err
+	}
 
-	// Update menu items using helper functions
-	mCurrentSession.SetTitle(formatUsageWithReset(limits.FiveHour, "5-Hour Session:"))
-	mWeeklyAll.SetTitle(formatUsageWithReset(limits.SevenDay, "Weekly (All):"))
-	mWeeklyOpus.SetTitle(formatUsageWithReset(limits.SevenDayOpus, "Weekly (Opus):"))
+	// Canceled on shutdown so quitting doesn't wait out a slow request; the
+	// client's timeout still bounds each request
+	limits, err := client.GetUsageLimitsContext(appCtx)
+	if isAuthError(err) {
+		// The secret manager may hold a newer key than the cached one
+		if fresh, ok := refreshCommandSession(client); ok {
+			client = fresh
+			limits, err = client.GetUsageLimitsContext(appCtx)
+		}
+	}
+	logFetchStatus(err)
+	streak := fetchFailures.record(err)
+	if err != nil {
+		return streak, err
+	}
+	orgIDSaved.Do(func() { saveOrganizationID(client.OrganizationID()) })
 
 	// Store limits for instant display switching (thread-safe)
 	limitsMutex.Lock()
+	previous := lastLimits
 	lastLimits = limits
 	limitsMutex.Unlock()
 
-	// Update menu bar display
-	updateMenuBarDisplay(limits)
+	usageEvents.publish(UsageUpdated{
+		Previous: previous,
+		Limits:   limits,
+		Changed:  !sameUsage(previous, limits),
+		At:       time.Now(),
+	})
+	return streak, nil
 }
 
-func onExit() {
-	if appCancel != nil {
-		appCancel()
+// refetchIfStale fetches again when the API still reports a reset time that
+// has passed, which happens right around a reset, rather than show the
+// countdown vanishing until the next poll
+func refetchIfStale(event UsageUpdated) {
+	if stale, ok := staleResetTime(event.Limits, event.At); ok && staleRefetchedFor.Swap(stale.Unix()) != stale.Unix() {
+		log.Printf("Reset time %s has passed, fetching again\n", stale.Format(time.RFC3339))
+		startUpdate()
+	}
+}
+
+// Helper function to find a reset time that passed more than staleResetGrace
+// before now, returning the earliest
+func staleResetTime(limits *claude.UsageLimits, now time.Time) (time.Time, bool) {
+	var stale time.Time
+	for _, kind := range limitKinds {
+		limit := kind.Get(limits)
+		if limit == nil || limit.ResetsAtTime.IsZero() || !limit.ResetsAtTime.Before(now.Add(-staleResetGrace)) {
+			continue
+		}
+		if stale.IsZero() || limit.ResetsAtTime.Before(stale) {
+			stale = limit.ResetsAtTime
+		}
+	}
+	return stale, !stale.IsZero()
+}
+
+// logFetchStatus logs the first fetch and each switch between success and
+// failure, so the log records the last API status without a line per poll
+func logFetchStatus(err error) {
+	state := fetchStateOK
+	if err != nil {
+		state = fetchStateFailed
+	}
+	if fetchState.Swap(state) == state {
+		return
+	}
+
+	if err != nil {
+		log.Printf("%s: %v\n", apiFailedMessage, err)
+	} else {
+		log.Println(apiOKMessage)
+	}
+}
+
+// saveOrganizationID persists an organization ID the client resolved on its
+// own, so restarts don't depend on the organizations endpoint again
+func saveOrganizationID(id string) {
+	if id == "" || LoadConfig().OrganizationID == id {
+		return
+	}
+	if err := UpdateConfig(func(config *Config) { config.OrganizationID = id }); err != nil {
+		log.Printf("Warning: Failed to save organization ID: %v\n", err)
+	}
+}
+
+// redetectOrganization discards the saved organization ID and resolves it
+// again, for when it's stale after switching or joining an organization.
+// The new ID is saved and used from the next fetch on.
+func redetectOrganization() {
+	session, err := LoadAuthSession()
+	if err != nil {
+		log.Printf("Warning: Failed to re-detect organization: %v\n", err)
+		return
+	}
+	session.OrganizationID = ""
+	client := createClientFromSession(session)
+
+	org, err := client.GetOrganization()
+	if err != nil {
+		log.Printf("Warning: Failed to re-detect organization: %v\n", err)
+		if err := sendNotification("Claude Monitor Lite", "Couldn't re-detect the organization: "+err.Error()); err != nil {
+			log.Printf("Warning: Failed to send notification: %v\n", err)
+		}
+		return
+	}
+	saveOrganizationID(org.ID)
+	claudeClient.Store(client)
+	log.Printf("Organization re-detected: %s\n", org.ID)
+
+	// The plan belongs to the organization, so it may have changed too
+	setPlan := func(config *Config) { config.AccountPlan = org.Plan }
+	updateAppConfig(setPlan)
+	if err := UpdateConfig(setPlan); err != nil {
+		log.Printf("Warning: Failed to save plan: %v\n", err)
+	}
+	updateAccountMenu()
+
+	// Name the organization picked, since an account in several only gets
+	// the first one listed
+	name := org.Name
+	if name == "" {
+		name = org.ID
+	}
+	if err := sendNotification("Claude Monitor Lite", "Monitoring organization "+name); err != nil {
+		log.Printf("Warning: Failed to send notification: %v\n", err)
+	}
+	startManualUpdate()
+}
+
+// showFetchError shows a failed fetch in the menu bar
+func showFetchError(err error) {
+	if claudeClient.Load() == nil {
+		setStatusTitle(iconAuthExpired, "Not logged in")
+		return
+	}
+
+	// Check the typed errors: expired sessions and blocks need action,
+	// anything else is likely a network blip
+	switch {
+	case errors.Is(err, claude.ErrAuthFailed):
+		setStatusTitle(iconAuthExpired, "Expired")
+		showFetchErrorItem("Session expired - please login again")
+	case errors.Is(err, claude.ErrBlocked):
+		setStatusTitle(iconBlocked, "Blocked")
+		showFetchErrorItem("Blocked by Cloudflare - open claude.ai in browser")
+	case claude.CategoryOf(err) == claude.CategoryRateLimit:
+		setStatusTitle(iconError, "Rate limited")
+		showFetchErrorItem("Rate limited by claude.ai - retrying later")
+	case claude.CategoryOf(err) == claude.CategoryNotFound:
+		// Most likely a saved organization ID the account no longer has
+		setStatusTitle(iconError, "Error")
+		showFetchErrorItem("Organization not found - try Re-detect Organization")
+	default:
+		setStatusTitle(iconError, "Error")
+		showFetchErrorItem("Error loading data")
+	}
+}
+
+// Helper function to show why the last fetch failed above the usage items
+func showFetchErrorItem(message string) {
+	mFetchError.SetTitle(message)
+	mFetchError.Show()
+}
+
+// updateUsageMenu shows limits in the menu items and tooltip
+func updateUsageMenu(limits *claude.UsageLimits) {
+	mFetchError.Hide()
+	for i, kind := range limitItemKinds {
+		mLimitItems[i].SetTitle(formatUsageItem(kind.Get(limits), kind.Label+":", kind.Key))
+	}
+	updateResetTimesMenu(limits)
+	systray.SetTooltip(formatTooltip(limits))
+}
+
+// addResetTimesMenu adds the Reset Times submenu. Its items are informational
+// and stay hidden until their limit has data.
+func addResetTimesMenu() {
+	mResetTimes = systray.AddMenuItem("Reset Times", "When each limit resets")
+	for _, kind := range menuKinds {
+		item := mResetTimes.AddSubMenuItem(kind.Label+": --", "")
+		item.Disable()
+		item.Hide()
+		mResetItems = append(mResetItems, item)
+	}
+}
+
+// addRecentMenu adds the Recent submenu summarizing the history file
+func addRecentMenu() {
+	mRecent = systray.AddMenuItem("Recent (5h)", "Usage range over the last 5 hours, from the history file")
+	for _, kind := range menuKinds {
+		item := mRecent.AddSubMenuItem(kind.Label+": --", "")
+		item.Disable()
+		item.Hide()
+		mRecentItems = append(mRecentItems, item)
+	}
+	mRecentEmpty = mRecent.AddSubMenuItem("No history", "")
+	mRecentEmpty.Disable()
+	updateRecentMenu()
+}
+
+// updateRecentMenu refills the Recent submenu from the recorded history. The
+// tray can't tell when a menu opens, so it's refreshed when history is
+// written instead.
+func updateRecentMenu() {
+	if !currentConfig().RecordHistory {
+		for _, item := range mRecentItems {
+			item.Hide()
+		}
+		mRecentEmpty.SetTitle("No history (set recordHistory to keep it)")
+		mRecentEmpty.Show()
+		return
+	}
+
+	now := time.Now()
+	entries := recentHistory(now)
+	shown := false
+	for i, kind := range menuKinds {
+		summary, ok := summarizeHistory(entries, kind.Get, now)
+		if !ok {
+			mRecentItems[i].Hide()
+			continue
+		}
+		mRecentItems[i].SetTitle(formatRecentItem(kind.Label, summary))
+		mRecentItems[i].Show()
+		shown = true
+	}
+
+	if shown {
+		mRecentEmpty.Hide()
+	} else {
+		mRecentEmpty.SetTitle("No history")
+		mRecentEmpty.Show()
+	}
+}
+
+// Helper function to format a Recent item, e.g.
+// "5-Hour Session: 12–48%, avg 30%"
+func formatRecentItem(label string, summary usageSummary) string {
+	return fmt.Sprintf("%s: %s–%s%%, avg %s%%", label,
+		formatPercentNumber(summary.Min, false), formatPercentNumber(summary.Max, false), formatPercentNumber(summary.Avg, false))
+}
+
+// updateResetTimesMenu shows each limit's reset time, hiding limits the
+// account doesn't have
+func updateResetTimesMenu(limits *claude.UsageLimits) {
+	for i, kind := range menuKinds {
+		limit := kind.Get(limits)
+		if limit == nil {
+			mResetItems[i].Hide()
+			continue
+		}
+		mResetItems[i].SetTitle(formatResetItem(limit, kind.Label, kind.Key))
+		mResetItems[i].Show()
+	}
+}
+
+// warmUpStats performs the first fetch, retrying briefly so the menu bar
+// populates as soon as the network comes up (e.g. when launched at login).
+// The first attempt goes ahead even over the daily budget, so a restart
+// always has something to show; the retries count against it as usual.
+func warmUpStats() {
+	for attempt := 1; attempt <= startupRetryAttempts; attempt++ {
+		err := updateStats(attempt == 1)
+		if err == nil || errors.Is(err, claude.ErrAuthFailed) || errors.Is(err, errBudgetExhausted) {
+			return
+		}
+
+		select {
+		case <-appCtx.Done():
+			return
+		case <-time.After(startupRetryDelay):
+		}
 	}
+}
+
+func onExit() {
 	cleanup()
 }
@@ -0,0 +1,845 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+func TestRoundUtilization(t *testing.T) {
+	tests := []struct {
+		utilization float64
+		want        int
+	}{
+		{0, 0},
+		{0.4, 0},
+		{0.5, 1},
+		{49.5, 50},
+		{99.4, 99},
+		{99.5, 100},
+		{100, 100},
+	}
+
+	for _, tt := range tests {
+		if got := roundUtilization(tt.utilization); got != tt.want {
+			t.Errorf("roundUtilization(%v) = %d, want %d", tt.utilization, got, tt.want)
+		}
+	}
+}
+
+func TestRenderUsageBar(t *testing.T) {
+	tests := []struct {
+		utilization float64
+		useEmoji    bool
+		want        string
+	}{
+		{0, true, "⬜⬜⬜⬜⬜"},
+		{9.9, true, "⬜⬜⬜⬜⬜"},
+		{10, true, "🟩⬜⬜⬜⬜"},
+		{60, true, "🟩🟩🟩⬜⬜"},
+		{100, true, "🟩🟩🟩🟩🟩"},
+		{130, true, "🟩🟩🟩🟩🟩"},
+		{-5, true, "⬜⬜⬜⬜⬜"},
+		{0, false, "[-----]"},
+		{60, false, "[###--]"},
+		{100, false, "[#####]"},
+	}
+
+	for _, tt := range tests {
+		if got := renderUsageBar(tt.utilization, tt.useEmoji); got != tt.want {
+			t.Errorf("renderUsageBar(%v, %v) = %q, want %q", tt.utilization, tt.useEmoji, got, tt.want)
+		}
+	}
+}
+
+func TestRoundMinutes(t *testing.T) {
+	tests := []struct {
+		minutes   int
+		precision int
+		want      int
+	}{
+		{0, 10, 0},
+		{4, 10, 0},
+		{5, 10, 10},
+		{14, 10, 10},
+		{15, 10, 20},
+		{54, 10, 50},
+		{55, 10, 60},
+		{59, 10, 60},
+		{37, 1, 37},
+		{59, 1, 59},
+		{7, 15, 0},
+		{8, 15, 15},
+		{52, 15, 45},
+		{53, 15, 60},
+		{52, 7, 49},
+		{53, 7, 56},
+		{57, 7, 56},
+		{58, 7, 60},
+		{59, 7, 60},
+		{53, 9, 54},
+		{59, 9, 60},
+		{50, 45, 45},
+		{52, 45, 45},
+		{53, 45, 60},
+		{55, 45, 60},
+		{22, 45, 0},
+		{23, 45, 45},
+		{15, 25, 25},
+		{54, 25, 50},
+		{55, 25, 60},
+		{29, 60, 0},
+		{30, 60, 60},
+	}
+
+	for _, tt := range tests {
+		if got := roundMinutes(tt.minutes, tt.precision); got != tt.want {
+			t.Errorf("roundMinutes(%d, %d) = %d, want %d", tt.minutes, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateTimeUntilReset(t *testing.T) {
+	// Seconds past the minute are ignored, so a fixed now mid-minute gives
+	// the same countdowns as the minute itself
+	nowTruncated := time.Date(2025, 6, 12, 14, 7, 0, 0, time.Local)
+	now := nowTruncated.Add(42 * time.Second)
+
+	tests := []struct {
+		name        string
+		resetTime   time.Time
+		wantHours   int
+		wantMinutes int
+		wantValid   bool
+	}{
+		{"zero reset time", time.Time{}, 0, 0, false},
+		{"in the past", nowTruncated.Add(-time.Hour), 0, 0, false},
+		{"under a minute", nowTruncated.Add(30 * time.Second), 0, 0, true},
+		{"minutes only", nowTruncated.Add(40*time.Minute + 30*time.Second), 0, 40, true},
+		{"exactly one hour", nowTruncated.Add(time.Hour + 30*time.Second), 1, 0, true},
+		{"hours and minutes", nowTruncated.Add(4*time.Hour + 59*time.Minute + 30*time.Second), 4, 59, true},
+		{"over a day", nowTruncated.Add(98*time.Hour + 20*time.Minute + 30*time.Second), 98, 20, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hours, minutes, valid := calculateTimeUntilReset(tt.resetTime, now)
+			if hours != tt.wantHours || minutes != tt.wantMinutes || valid != tt.wantValid {
+				t.Errorf("calculateTimeUntilReset() = (%d, %d, %v), want (%d, %d, %v)",
+					hours, minutes, valid, tt.wantHours, tt.wantMinutes, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		hours, minutes int
+		sep            string
+		want           string
+	}{
+		{0, 0, " ", "0m"},
+		{0, 40, " ", "40m"},
+		{0, 59, " ", "59m"},
+		{1, 0, " ", "1h"},
+		{1, 1, " ", "1h 1m"},
+		{3, 20, " ", "3h 20m"},
+		{3, 20, "", "3h20m"},
+		{98, 0, "", "98h"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDuration(tt.hours, tt.minutes, tt.sep); got != tt.want {
+			t.Errorf("formatDuration(%d, %d, %q) = %q, want %q", tt.hours, tt.minutes, tt.sep, got, tt.want)
+		}
+	}
+}
+
+func TestFormatResetTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		resetTime time.Time
+		want      string
+	}{
+		{"on the hour", time.Date(2025, 6, 12, 14, 0, 0, 0, time.Local), "2025-06-12 14:00"},
+		{"rounds down", time.Date(2025, 6, 12, 14, 34, 0, 0, time.Local), "2025-06-12 14:30"},
+		{"rounds up", time.Date(2025, 6, 12, 14, 35, 0, 0, time.Local), "2025-06-12 14:40"},
+		{"minute rollover", time.Date(2025, 6, 12, 14, 55, 0, 0, time.Local), "2025-06-12 15:00"},
+		{"midnight rollover", time.Date(2025, 6, 12, 23, 56, 0, 0, time.Local), "2025-06-13 00:00"},
+		{"month rollover", time.Date(2025, 6, 30, 23, 58, 0, 0, time.Local), "2025-07-01 00:00"},
+		{"year rollover", time.Date(2025, 12, 31, 23, 55, 0, 0, time.Local), "2026-01-01 00:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatResetTime(tt.resetTime, "seven_day"); got != tt.want {
+				t.Errorf("formatResetTime() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatResetTimePrecision(t *testing.T) {
+	setTestConfig(t, func(c *Config) {
+		c.ResetPrecisionMinutes = 15
+		c.ResetPrecision = map[string]int{"five_hour": 1, "seven_day_opus": 9, "seven_day": -5}
+	})
+
+	tests := []struct {
+		name      string
+		resetTime time.Time
+		limitType string
+		want      string
+	}{
+		{"per-limit exact", time.Date(2025, 6, 12, 14, 37, 0, 0, time.Local), "five_hour", "2025-06-12 14:37"},
+		{"global rounds down", time.Date(2025, 6, 12, 14, 37, 0, 0, time.Local), "seven_day", "2025-06-12 14:30"},
+		{"global rounds up", time.Date(2025, 6, 12, 14, 38, 0, 0, time.Local), "seven_day", "2025-06-12 14:45"},
+		{"global midnight rollover", time.Date(2025, 6, 12, 23, 53, 0, 0, time.Local), "seven_day", "2025-06-13 00:00"},
+		{"non-divisor", time.Date(2025, 6, 12, 14, 50, 0, 0, time.Local), "seven_day_opus", "2025-06-12 14:54"},
+		{"non-divisor rollover", time.Date(2025, 12, 31, 23, 59, 0, 0, time.Local), "seven_day_opus", "2026-01-01 00:00"},
+		{"negative override uses global", time.Date(2025, 6, 12, 14, 38, 0, 0, time.Local), "seven_day", "2025-06-12 14:45"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatResetTime(tt.resetTime, tt.limitType); got != tt.want {
+				t.Errorf("formatResetTime(%s) = %q, want %q", tt.limitType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTooltip(t *testing.T) {
+	limits := &claude.UsageLimits{
+		FiveHour: &claude.UsageLimit{Utilization: 42.4},
+		SevenDay: &claude.UsageLimit{Utilization: 10},
+	}
+
+	want := "Claude Monitor Lite\n5-Hour Session: 42%\nWeekly (All): 10%\nWeekly (Opus): --"
+	if got := formatTooltip(limits); got != want {
+		t.Errorf("formatTooltip() = %q, want %q", got, want)
+	}
+}
+
+func TestSharedWeeklyReset(t *testing.T) {
+	setTestConfig(t, func(c *Config) { c.CombineWeeklyResets = true })
+
+	reset := time.Date(2025, 6, 12, 14, 30, 0, 0, time.UTC)
+	weekly := func(all, opus time.Time) *claude.UsageLimits {
+		return &claude.UsageLimits{
+			SevenDay:     &claude.UsageLimit{ResetsAtTime: all},
+			SevenDayOpus: &claude.UsageLimit{ResetsAtTime: opus},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		limits *claude.UsageLimits
+		want   bool
+	}{
+		{"identical", weekly(reset, reset), true},
+		{"within tolerance", weekly(reset, reset.Add(2*time.Minute)), true},
+		{"different days", weekly(reset, reset.Add(24*time.Hour)), false},
+		{"missing reset", weekly(reset, time.Time{}), false},
+		{"missing opus limit", &claude.UsageLimits{SevenDay: &claude.UsageLimit{ResetsAtTime: reset}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := sharedWeeklyReset(tt.limits); got != tt.want {
+				t.Errorf("sharedWeeklyReset() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetColorIndicator(t *testing.T) {
+	setTestConfig(t, func(c *Config) {
+		c.UseEmoji = false
+		c.ColorThresholds = map[string]ColorThresholds{
+			"seven_day_opus": {Yellow: 30, Red: 60},
+			"seven_day":      {Red: 90},
+		}
+	})
+
+	tests := []struct {
+		name        string
+		utilization float64
+		limitType   string
+		want        string
+	}{
+		{"global ok", 40, "five_hour", "[OK]"},
+		{"global warn", 60, "five_hour", "[WARN]"},
+		{"global crit", 80, "five_hour", "[CRIT]"},
+		{"override warn", 40, "seven_day_opus", "[WARN]"},
+		{"override crit", 60, "seven_day_opus", "[CRIT]"},
+		{"partial override keeps yellow", 85, "seven_day", "[WARN]"},
+		{"partial override red", 90, "seven_day", "[CRIT]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getColorIndicator(tt.utilization, tt.limitType); got != tt.want {
+				t.Errorf("getColorIndicator(%v, %q) = %q, want %q", tt.utilization, tt.limitType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPercent(t *testing.T) {
+	tests := []struct {
+		name          string
+		utilization   float64
+		showRemaining bool
+		want          string
+	}{
+		{"used", 42.4, false, "42%"},
+		{"remaining", 42.4, true, "58% left"},
+		{"over the limit", 104, true, "0% left"},
+		{"nearly exhausted", 99.6, true, "0.4% left"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestConfig(t, func(c *Config) { c.ShowRemaining = tt.showRemaining })
+			if got := formatPercent(tt.utilization); got != tt.want {
+				t.Errorf("formatPercent(%v) = %q, want %q", tt.utilization, got, tt.want)
+			}
+		})
+	}
+}
+
+// setTestConfig applies mutate to appConfig until the test ends
+func setTestConfig(t *testing.T, mutate func(*Config)) {
+	t.Helper()
+	saved := appConfig.Load()
+	t.Cleanup(func() { appConfig.Store(saved) })
+	updateAppConfig(mutate)
+}
+
+// Run with -race: the menu loop, fetch goroutines and reloads all touch
+// appConfig at once
+func TestAppConfigConcurrentAccess(t *testing.T) {
+	setTestConfig(t, func(c *Config) {})
+	limits := &claude.UsageLimits{LastUpdated: time.Now()}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for range 200 {
+				formatPercent(42)
+				isStale(limits, time.Now())
+				getSelectedLimit(limits, currentConfig().MenuBarIndicator)
+			}
+		})
+	}
+	wg.Go(func() {
+		for i := range 200 {
+			updateAppConfig(func(c *Config) { c.ShowRemaining = !c.ShowRemaining })
+			updateAppConfig(func(c *Config) { c.StaleAfterMinutes = i })
+		}
+	})
+	wg.Go(func() {
+		for range 50 {
+			setAppConfig(Config{MenuBarIndicator: "weeklyAll"})
+		}
+	})
+	wg.Wait()
+
+	// Updates start from the current settings, so none is lost
+	setAppConfig(Config{})
+	var updates sync.WaitGroup
+	for range 100 {
+		updates.Go(func() {
+			updateAppConfig(func(c *Config) { c.MinRefreshSeconds++ })
+		})
+	}
+	updates.Wait()
+	if got := currentConfig().MinRefreshSeconds; got != 100 {
+		t.Errorf("MinRefreshSeconds after 100 concurrent increments = %d, want 100", got)
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	now := time.Date(2025, 6, 12, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		staleAfter int
+		updated    time.Time
+		want       bool
+	}{
+		{"fresh", 0, now.Add(-time.Minute), false},
+		{"past default", 0, now.Add(-11 * time.Minute), true},
+		{"within custom threshold", 30, now.Add(-11 * time.Minute), false},
+		{"past custom threshold", 30, now.Add(-31 * time.Minute), true},
+		{"never updated", 0, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestConfig(t, func(c *Config) { c.StaleAfterMinutes = tt.staleAfter })
+			limits := &claude.UsageLimits{LastUpdated: tt.updated}
+			if got := isStale(limits, now); got != tt.want {
+				t.Errorf("isStale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithinErrorGrace(t *testing.T) {
+	network := errors.New("failed to fetch usage limits: dial tcp: i/o timeout")
+
+	tests := []struct {
+		name       string
+		errorAfter int
+		err        error
+		streak     int
+		want       bool
+	}{
+		{"first failure", 2, network, 1, true},
+		{"second failure", 2, network, 2, false},
+		{"longer grace", 3, network, 2, true},
+		{"grace disabled", 1, network, 1, false},
+		{"zero shows at once", 0, network, 1, false},
+		{"expired session", 2, fmt.Errorf("%w (status 401)", claude.ErrAuthFailed), 1, false},
+		{"blocked", 2, fmt.Errorf("%w (status 403)", claude.ErrBlocked), 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestConfig(t, func(c *Config) { c.ErrorAfterFailures = tt.errorAfter })
+			if got := withinErrorGrace(tt.err, tt.streak); got != tt.want {
+				t.Errorf("withinErrorGrace(%v, %d) = %v, want %v", tt.err, tt.streak, got, tt.want)
+			}
+		})
+	}
+}
+
+// A fetch that finishes before onReady has built the menu must draw after
+// it, not before
+func TestMenuReadyGateOrdering(t *testing.T) {
+	gate := newReadyGate()
+	var mu sync.Mutex
+	var order []string
+	record := func(step string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, step)
+	}
+
+	fetched := make(chan struct{})
+	drawn := make(chan bool)
+	go func() {
+		// The fetch itself needs no menu
+		close(fetched)
+		ok := gate.wait(context.Background())
+		if ok {
+			record("draw")
+		}
+		drawn <- ok
+	}()
+
+	<-fetched
+	select {
+	case <-drawn:
+		t.Fatal("wait() returned before the gate opened")
+	case <-time.After(20 * time.Millisecond):
+	}
+	record("menu built")
+	gate.open()
+	gate.open() // A second open is harmless
+
+	if ok := <-drawn; !ok {
+		t.Fatal("wait() = false after open, want true")
+	}
+	if want := []string{"menu built", "draw"}; !slices.Equal(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+	if !gate.wait(context.Background()) {
+		t.Error("wait() on an open gate = false, want true")
+	}
+
+	// Shutdown before the menu exists mustn't leave fetches hanging
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if newReadyGate().wait(ctx) {
+		t.Error("wait() with a canceled context = true, want false")
+	}
+}
+
+func TestAgingSessionDays(t *testing.T) {
+	now := time.Date(2025, 6, 12, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		warnAfter int
+		savedAt   time.Time
+		wantDays  int
+		want      bool
+	}{
+		{"recent", 25, now.AddDate(0, 0, -3), 3, false},
+		{"almost aging", 25, now.AddDate(0, 0, -25).Add(time.Hour), 24, false},
+		{"aging", 25, now.AddDate(0, 0, -25), 25, true},
+		{"custom threshold", 10, now.AddDate(0, 0, -12), 12, true},
+		{"disabled", 0, now.AddDate(0, 0, -90), 0, false},
+		{"unknown age", 25, time.Time{}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestConfig(t, func(c *Config) { c.SessionWarnAfterDays = tt.warnAfter })
+			days, got := agingSessionDays(tt.savedAt, now)
+			if days != tt.wantDays || got != tt.want {
+				t.Errorf("agingSessionDays() = %d, %v, want %d, %v", days, got, tt.wantDays, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameUsage(t *testing.T) {
+	reset := time.Date(2025, 6, 12, 18, 0, 0, 0, time.UTC)
+	base := func() *claude.UsageLimits {
+		return &claude.UsageLimits{
+			FiveHour:    &claude.UsageLimit{Utilization: 42, ResetsAtTime: reset},
+			SevenDay:    &claude.UsageLimit{Utilization: 10, ResetsAtTime: reset.Add(72 * time.Hour)},
+			LastUpdated: reset.Add(-time.Hour),
+		}
+	}
+
+	refetched := base()
+	refetched.LastUpdated = refetched.LastUpdated.Add(30 * time.Second)
+	moreUsage := base()
+	moreUsage.FiveHour.Utilization = 43
+	newWindow := base()
+	newWindow.FiveHour.ResetsAtTime = reset.Add(5 * time.Hour)
+	opusAppeared := base()
+	opusAppeared.SevenDayOpus = &claude.UsageLimit{Utilization: 0}
+
+	tests := []struct {
+		name string
+		b    *claude.UsageLimits
+		want bool
+	}{
+		{"refetched unchanged", refetched, true},
+		{"utilization changed", moreUsage, false},
+		{"reset time changed", newWindow, false},
+		{"limit appeared", opusAppeared, false},
+		{"first fetch", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameUsage(base(), tt.b); got != tt.want {
+				t.Errorf("sameUsage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatUsageItem(t *testing.T) {
+	reset := time.Now().Add(3 * time.Hour)
+
+	tests := []struct {
+		name  string
+		limit *claude.UsageLimit
+		want  string
+	}{
+		{"no data", nil, "5-Hour Session: --"},
+		{"idle", &claude.UsageLimit{Utilization: 0}, "5-Hour Session: 0% (no active session)"},
+		{"in use", &claude.UsageLimit{Utilization: 42, ResetsAtTime: reset}, "5-Hour Session: 42%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatUsageItem(tt.limit, "5-Hour Session:", "five_hour"); got != tt.want {
+				t.Errorf("formatUsageItem() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatStatusLine(t *testing.T) {
+	setTestConfig(t, func(c *Config) { c.UseEmoji = true })
+
+	limits := &claude.UsageLimits{
+		FiveHour:     &claude.UsageLimit{Utilization: 32},
+		SevenDay:     &claude.UsageLimit{Utilization: 55},
+		SevenDayOpus: &claude.UsageLimit{Utilization: 91},
+	}
+
+	if got, want := formatStatusLine(limits, false), "5h:32% 7d:55% opus:91%"; got != want {
+		t.Errorf("formatStatusLine() = %q, want %q", got, want)
+	}
+	if got, want := formatStatusLine(limits, true), "🟢5h:32% 🟡7d:55% 🔴opus:91%"; got != want {
+		t.Errorf("formatStatusLine() with indicators = %q, want %q", got, want)
+	}
+
+	limits.SevenDayOpus = nil
+	if got, want := formatStatusLine(limits, false), "5h:32% 7d:55%"; got != want {
+		t.Errorf("formatStatusLine() without Opus = %q, want %q", got, want)
+	}
+
+	setTestConfig(t, func(c *Config) { c.DisplayLimits = []string{"seven_day", "five_hour"} })
+	if got, want := formatStatusLine(limits, false), "7d:55% 5h:32%"; got != want {
+		t.Errorf("formatStatusLine() with displayLimits = %q, want %q", got, want)
+	}
+}
+
+func TestIdleLimitLabel(t *testing.T) {
+	reset := time.Now().Add(48 * time.Hour)
+
+	tests := []struct {
+		name        string
+		limit       *claude.UsageLimit
+		limitType   string
+		unusedLabel string
+		want        string
+	}{
+		{"session idle", &claude.UsageLimit{Utilization: 0}, "five_hour", "", "no active session"},
+		{"session running", &claude.UsageLimit{Utilization: 0, ResetsAtTime: reset}, "five_hour", "", ""},
+		{"weekly never used", &claude.UsageLimit{Utilization: 0}, "seven_day", "", "unused"},
+		{"weekly just reset", &claude.UsageLimit{Utilization: 0, ResetsAtTime: reset}, "seven_day", "", ""},
+		{"weekly tiny usage", &claude.UsageLimit{Utilization: 0.2}, "seven_day", "", ""},
+		{"custom label", &claude.UsageLimit{Utilization: 0}, "seven_day_opus", "not yet", "not yet"},
+		{"no data", nil, "seven_day", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestConfig(t, func(c *Config) { c.UnusedLabel = tt.unusedLabel })
+			if got := idleLimitLabel(tt.limit, tt.limitType); got != tt.want {
+				t.Errorf("idleLimitLabel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatResetItem(t *testing.T) {
+	if got := formatResetItem(&claude.UsageLimit{}, "Weekly (All)", "seven_day"); got != "Weekly (All): not scheduled" {
+		t.Errorf("formatResetItem() without a reset = %q", got)
+	}
+
+	reset := time.Now().Add(26 * time.Hour)
+	got := formatResetItem(&claude.UsageLimit{ResetsAtTime: reset}, "Weekly (All)", "seven_day")
+	if want := "Weekly (All): " + formatResetTime(reset, "seven_day") + " (in "; !strings.HasPrefix(got, want) {
+		t.Errorf("formatResetItem() = %q, want prefix %q", got, want)
+	}
+}
+
+func TestFormatCountdownFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		daysAfter int
+		limitType string
+		hours     int
+		minutes   int
+		want      string
+	}{
+		{"disabled", 0, "seven_day", 98, 20, "98h 20m"},
+		{"weekly in days", 24, "seven_day", 98, 20, "4d 2h"},
+		{"whole days", 24, "seven_day_opus", 72, 10, "3d"},
+		{"under a day", 24, "seven_day", 23, 50, "23h 50m"},
+		{"custom threshold", 48, "seven_day", 30, 0, "30h"},
+		{"past custom threshold", 48, "seven_day", 50, 0, "2d 2h"},
+		{"threshold below a day", 6, "seven_day", 10, 30, "10h 30m"},
+		{"five-hour stays in hours", 1, "five_hour", 4, 30, "4h 30m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestConfig(t, func(c *Config) { c.WeeklyDaysAfterHours = tt.daysAfter })
+			if got := formatCountdownFor(tt.limitType, tt.hours, tt.minutes, " "); got != tt.want {
+				t.Errorf("formatCountdownFor(%q, %d, %d) = %q, want %q", tt.limitType, tt.hours, tt.minutes, got, tt.want)
+			}
+		})
+	}
+
+	// The console drops the exact time along with the minutes
+	setTestConfig(t, func(c *Config) { c.WeeklyDaysAfterHours = 24 })
+	weekly := &claude.UsageLimit{Utilization: 40, ResetsAtTime: time.Now().Add(98*time.Hour + 25*time.Minute)}
+	if got, want := formatConsoleUsage(weekly, "Weekly (All):", "seven_day", ""), "Weekly (All):   40%  (resets in 4d 2h)\n"; got != want {
+		t.Errorf("formatConsoleUsage() = %q, want %q", got, want)
+	}
+}
+
+func TestIsNoActiveSession(t *testing.T) {
+	reset := time.Now().Add(3 * time.Hour)
+
+	tests := []struct {
+		name  string
+		limit *claude.UsageLimit
+		want  bool
+	}{
+		{"idle", &claude.UsageLimit{Utilization: 0}, true},
+		{"rounds to zero", &claude.UsageLimit{Utilization: 0.3}, true},
+		{"zero mid-session", &claude.UsageLimit{Utilization: 0, ResetsAtTime: reset}, false},
+		{"in use", &claude.UsageLimit{Utilization: 12, ResetsAtTime: reset}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNoActiveSession(tt.limit); got != tt.want {
+				t.Errorf("isNoActiveSession() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExitCodeFor(t *testing.T) {
+	clientError := func(category claude.ErrorCategory, err error) error {
+		return &claude.ClientError{Category: category, Err: err}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"login abandoned", fmt.Errorf("%w: no session key provided", errLoginIncomplete), exitNotAuthenticated},
+		{"auth failed", fmt.Errorf("%w (status 401)", claude.ErrAuthFailed), exitNotAuthenticated},
+		{"session expired", claude.ErrSessionExpired, exitNotAuthenticated},
+		{"blocked", clientError(claude.CategoryBlocked, fmt.Errorf("%w (status 403)", claude.ErrBlocked)), exitNetworkError},
+		{"transport", clientError(claude.CategoryNetwork, errors.New("dial tcp: no such host")), exitNetworkError},
+		{"wrapped transport", fmt.Errorf("failed to get organization ID: %w", clientError(claude.CategoryNetwork, errors.New("i/o timeout"))), exitNetworkError},
+		{"unexpected status", clientError(claude.CategoryServer, errors.New("unexpected status 500")), exitError},
+		{"parse", clientError(claude.CategoryParse, errors.New("invalid character '<'")), exitError},
+		{"uncategorized", errors.New("failed to read config"), exitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCodeFor(tt.err); got != tt.want {
+				t.Errorf("exitCodeFor(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatFixedDuration(t *testing.T) {
+	const fs = "\u2007" // figure space
+	tests := []struct {
+		hours, minutes, hourDigits int
+		want                       string
+	}{
+		{2, 5, 1, "2h05m"},
+		{0, 45, 1, "0h45m"},
+		{9, 30, 3, fs + fs + "9h30m"},
+		{142, 0, 3, "142h00m"},
+	}
+
+	for _, tt := range tests {
+		if got := formatFixedDuration(tt.hours, tt.minutes, tt.hourDigits); got != tt.want {
+			t.Errorf("formatFixedDuration(%d, %d, %d) = %q, want %q", tt.hours, tt.minutes, tt.hourDigits, got, tt.want)
+		}
+	}
+}
+
+func TestFormatPercentNumber(t *testing.T) {
+	tests := []struct {
+		rounding  string
+		percent   float64
+		remaining bool
+		want      string
+	}{
+		{"round", 99.5, false, "99.5"},
+		{"round", 99.6, false, "99.6"},
+		{"round", 99.96, false, "99.9"},
+		{"round", 100, false, "100"},
+		{"round", 0.4, false, "0"},
+		{"round", 0.4, true, "0.4"},
+		{"round", 0.04, true, "0.1"},
+		{"round", 42.5, false, "43"},
+		{"floor", 99.5, false, "99"},
+		{"floor", 99.6, false, "99"},
+		{"floor", 0.4, false, "0"},
+		{"floor", 0.4, true, "0.4"},
+		{"ceil", 99.5, false, "99.5"},
+		{"ceil", 99.6, false, "99.6"},
+		{"ceil", 0.4, false, "1"},
+		{"ceil", 42.1, false, "43"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s_%v_%v", tt.rounding, tt.percent, tt.remaining), func(t *testing.T) {
+			// showRemaining only matters through the remaining argument
+			setTestConfig(t, func(c *Config) {
+				c.UtilizationRounding = tt.rounding
+				c.ShowRemaining = !tt.remaining
+			})
+			if got := formatPercentNumber(tt.percent, tt.remaining); got != tt.want {
+				t.Errorf("formatPercentNumber(%v, %v) = %q, want %q", tt.percent, tt.remaining, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadPIDFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantPID  int
+		wantKept bool
+	}{
+		{"pid and executable", "1234\n/usr/local/bin/claude-monitor-lite\n", 1234, true},
+		{"pid only", "1234\n", 1234, true},
+		{"empty", "", 0, false},
+		{"cut short", "\n/usr/local/bin/claude", 0, false},
+		{"garbage", "not-a-pid\n", 0, false},
+		{"zero", "0\n", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "monitor.pid")
+			if err := os.WriteFile(path, []byte(tt.content), pidFilePermissions); err != nil {
+				t.Fatal(err)
+			}
+
+			pid, _, err := readPIDFile(path)
+			if pid != tt.wantPID || (err == nil) != tt.wantKept {
+				t.Errorf("readPIDFile() = %d, %v, want %d", pid, err, tt.wantPID)
+			}
+			if _, statErr := os.Stat(path); (statErr == nil) != tt.wantKept {
+				t.Errorf("file kept = %v, want %v", statErr == nil, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestStaleResetTime(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		limits *claude.UsageLimits
+		want   time.Time
+		stale  bool
+	}{
+		{"no limits", &claude.UsageLimits{}, time.Time{}, false},
+		{"future reset", &claude.UsageLimits{FiveHour: &claude.UsageLimit{ResetsAtTime: now.Add(time.Hour)}}, time.Time{}, false},
+		{"within grace", &claude.UsageLimits{FiveHour: &claude.UsageLimit{ResetsAtTime: now.Add(-30 * time.Second)}}, time.Time{}, false},
+		{"past reset", &claude.UsageLimits{FiveHour: &claude.UsageLimit{ResetsAtTime: now.Add(-5 * time.Minute)}}, now.Add(-5 * time.Minute), true},
+		{"earliest wins", &claude.UsageLimits{
+			FiveHour: &claude.UsageLimit{ResetsAtTime: now.Add(-5 * time.Minute)},
+			SevenDay: &claude.UsageLimit{ResetsAtTime: now.Add(-time.Hour)},
+		}, now.Add(-time.Hour), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stale := staleResetTime(tt.limits, now)
+			if stale != tt.stale || !got.Equal(tt.want) {
+				t.Errorf("staleResetTime() = %v, %v, want %v, %v", got, stale, tt.want, tt.stale)
+			}
+		})
+	}
+}
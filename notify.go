@@ -0,0 +1,405 @@
+// notify.go - Desktop notifications and sounds on usage threshold crossings
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+const (
+	defaultNotifyCooldown = 60 * time.Minute
+
+	// A drop in utilization this large (percentage points) between two
+	// fetches means the window reset, even if the reset time didn't change
+	resetDropPoints = 20.0
+)
+
+// failureCounter counts consecutive failed fetches, for both the
+// notifyAfterFailures notification and the errorAfterFailures grace period
+type failureCounter struct {
+	mu    sync.Mutex
+	count int
+}
+
+// record counts one fetch and returns the failures in a row, including this
+// one. A success resets the count to 0.
+func (f *failureCounter) record(err error) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		f.count = 0
+	} else {
+		f.count++
+	}
+	return f.count
+}
+
+// streak returns the failures in a row so far
+func (f *failureCounter) streak() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.count
+}
+
+// usageNotifier tracks when each limit/threshold pair last fired so
+// hovering around a threshold doesn't re-alert on every refresh
+type usageNotifier struct {
+	mu           sync.Mutex
+	lastNotified map[string]time.Time
+}
+
+var (
+	fetchFailures     = &failureCounter{}
+	notifier          = &usageNotifier{lastNotified: make(map[string]time.Time)}
+	soundNotifier     = &usageNotifier{lastNotified: make(map[string]time.Time)}
+	resetSoonNotifier = &usageNotifier{lastNotified: make(map[string]time.Time)}
+
+	// Notifications and sounds are held until this time (Unix seconds, 0 when
+	// not snoozed). Read from fetch goroutines.
+	snoozeUntil atomic.Int64
+
+	// Durations offered in the Snooze menu
+	snoozeDurations = []time.Duration{time.Hour, 2 * time.Hour, 4 * time.Hour, 8 * time.Hour}
+)
+
+// setSnooze holds notifications until t; the zero time clears the snooze
+func setSnooze(t time.Time) {
+	if t.IsZero() {
+		snoozeUntil.Store(0)
+		return
+	}
+	snoozeUntil.Store(t.Unix())
+}
+
+// snoozedUntil returns when the snooze ends, or false if it isn't active at now
+func snoozedUntil(now time.Time) (time.Time, bool) {
+	until := snoozeUntil.Load()
+	if until == 0 || now.Unix() >= until {
+		return time.Time{}, false
+	}
+	return time.Unix(until, 0), true
+}
+
+// check returns the notification messages due for limits at time now.
+// Only the highest crossed threshold per limit fires. A threshold re-arms
+// once utilization drops back below it (e.g. after a reset).
+func (n *usageNotifier) check(limits *claude.UsageLimits, thresholds []float64, cooldown time.Duration, now time.Time) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	sorted := append([]float64(nil), thresholds...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+
+	var messages []string
+	for _, kind := range limitKinds {
+		limit := kind.Get(limits)
+		if limit == nil {
+			continue
+		}
+
+		fired := false
+		for _, threshold := range sorted {
+			key := fmt.Sprintf("%s:%g", kind.Key, threshold)
+
+			if limit.Utilization < threshold {
+				delete(n.lastNotified, key)
+				continue
+			}
+
+			last, notified := n.lastNotified[key]
+			if notified && now.Sub(last) < cooldown {
+				// Still cooling down; lower thresholds are covered too
+				fired = true
+				continue
+			}
+
+			if !fired {
+				messages = append(messages, formatNotification(kind.Label, limit))
+			}
+			n.lastNotified[key] = now
+			fired = true
+		}
+	}
+	return messages
+}
+
+// checkCritical reports whether any limit crossed its own red threshold
+// (see colorThresholds), with the same cooldown and re-arming as check
+func (n *usageNotifier) checkCritical(limits *claude.UsageLimits, cooldown time.Duration, now time.Time) bool {
+	due := false
+	for _, kind := range limitKinds {
+		limit := kind.Get(limits)
+		if limit == nil {
+			continue
+		}
+		_, red := colorThresholds(kind.Key)
+		var single claude.UsageLimits
+		*kind.Field(&single) = limit
+		if len(n.check(&single, []float64{red}, cooldown, now)) > 0 {
+			due = true
+		}
+	}
+	return due
+}
+
+func formatNotification(label string, limit *claude.UsageLimit) string {
+	message := fmt.Sprintf("%s is at %s%%", label, formatPercentNumber(limit.Utilization, false))
+	if hours, minutes, ok := calculateTimeUntilReset(limit.ResetsAtTime, time.Now()); ok {
+		message += fmt.Sprintf(" (resets in %s)", formatDuration(hours, minutes, " "))
+	}
+	return message
+}
+
+// notifyUsage sends the notifications due after a fetch. Alerts during a
+// snooze or quiet hours are dropped, not queued; a threshold still crossed
+// afterwards fires on the next fetch.
+func notifyUsage(event UsageUpdated) {
+	if _, snoozed := snoozedUntil(event.At); snoozed || inQuietHours(event.At) {
+		return
+	}
+
+	notified := notifiableLimits(event.Limits)
+	checkNotifications(notified)
+	checkResetNotifications(notifiableLimits(event.Previous), notified)
+	checkBurnNotification(notified.FiveHour, event.At)
+	checkResetSoonNotification(notified.FiveHour)
+}
+
+// notifiableLimits returns limits without those left out of notifyLimits,
+// so every alert skips them
+func notifiableLimits(limits *claude.UsageLimits) *claude.UsageLimits {
+	config := currentConfig()
+	if limits == nil || len(config.NotifyLimits) == 0 {
+		return limits
+	}
+
+	filtered := *limits
+	for _, kind := range limitKinds {
+		if !slices.Contains(config.NotifyLimits, kind.Key) {
+			*kind.Field(&filtered) = nil
+		}
+	}
+	return &filtered
+}
+
+// notifyCooldown returns the configured cooldown between repeat notifications
+func notifyCooldown() time.Duration {
+	config := currentConfig()
+	if config.NotifyCooldownMinutes > 0 {
+		return time.Duration(config.NotifyCooldownMinutes) * time.Minute
+	}
+	return defaultNotifyCooldown
+}
+
+// checkNotifications sends any notifications and sounds due for freshly fetched limits
+func checkNotifications(limits *claude.UsageLimits) {
+	config := currentConfig()
+	cooldown := notifyCooldown()
+	now := time.Now()
+
+	if len(config.NotifyThresholds) > 0 {
+		for _, message := range notifier.check(limits, config.NotifyThresholds, cooldown, now) {
+			sendNotificationAsync(message)
+		}
+	}
+
+	// The chime has its own tracker so it fires on each limit's red
+	// threshold regardless of which notification thresholds are configured
+	if config.NotifySound != "" {
+		if soundNotifier.checkCritical(limits, cooldown, now) {
+			playSoundAsync(config.NotifySound)
+		}
+	}
+}
+
+// checkResetSoon reports whether the reset-soon notification is due: limit
+// resets within before, and it hasn't fired for this window yet
+func (n *usageNotifier) checkResetSoon(limit *claude.UsageLimit, before, cooldown time.Duration, now time.Time) bool {
+	if limit == nil || limit.ResetsAtTime.IsZero() {
+		return false
+	}
+	if until := limit.ResetsAtTime.Sub(now); until <= 0 || until > before {
+		return false
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	// Anything sent within the lead time was for this same window, so it
+	// re-arms on its own once the window resets
+	const key = "five_hour:reset-soon"
+	if last, ok := n.lastNotified[key]; ok && now.Sub(last) < max(cooldown, before) {
+		return false
+	}
+	n.lastNotified[key] = now
+	return true
+}
+
+// checkResetSoonNotification notifies once per window shortly before the
+// five-hour limit resets
+func checkResetSoonNotification(limit *claude.UsageLimit) {
+	config := currentConfig()
+	if config.NotifyBeforeResetMinutes <= 0 {
+		return
+	}
+
+	before := time.Duration(config.NotifyBeforeResetMinutes) * time.Minute
+	now := time.Now()
+	if !resetSoonNotifier.checkResetSoon(limit, before, notifyCooldown(), now) {
+		return
+	}
+
+	hours, minutes, _ := calculateTimeUntilReset(limit.ResetsAtTime, now)
+	message := fmt.Sprintf("Your 5-Hour Session limit resets in %s", formatDuration(hours, minutes, " "))
+	sendNotificationAsync(message)
+}
+
+// checkFailureNotification notifies once when notifyAfterFailures fetches in
+// a row have failed, which the menu bar alone makes easy to miss. streak is
+// the failures in a row including err; the next streak can notify again.
+func checkFailureNotification(err error, streak int) {
+	if threshold := currentConfig().NotifyAfterFailures; err == nil || threshold <= 0 || streak != threshold {
+		return
+	}
+	if _, snoozed := snoozedUntil(time.Now()); snoozed || inQuietHours(time.Now()) {
+		return
+	}
+
+	message := "Claude Monitor can't reach the API"
+	if isAuthError(err) {
+		message = "Claude Monitor's session has expired. Log in again to resume updates"
+	}
+	sendNotificationAsync(message)
+}
+
+// checkResetNotifications notifies when a limit resets between two fetches
+func checkResetNotifications(previous, current *claude.UsageLimits) {
+	if !currentConfig().NotifyOnReset || previous == nil {
+		return
+	}
+	for _, label := range detectResets(previous, current, time.Now()) {
+		message := fmt.Sprintf("Your %s limit has reset", label)
+		sendNotificationAsync(message)
+	}
+}
+
+// detectResets returns the labels of limits whose window reset between the
+// previous and current fetch: the old reset time passed and a new window
+// began, or utilization fell sharply. Limits that were unused are skipped.
+func detectResets(previous, current *claude.UsageLimits, now time.Time) []string {
+	var labels []string
+	for _, kind := range limitKinds {
+		before, after := kind.Get(previous), kind.Get(current)
+		if before == nil || after == nil || before.Utilization <= 0 {
+			continue
+		}
+
+		windowRolled := !before.ResetsAtTime.IsZero() && !now.Before(before.ResetsAtTime) &&
+			!after.ResetsAtTime.Equal(before.ResetsAtTime)
+		sharpDrop := before.Utilization-after.Utilization >= resetDropPoints
+		if windowRolled || sharpDrop {
+			labels = append(labels, kind.Label)
+		}
+	}
+	return labels
+}
+
+// playSound plays a sound file, or a named system sound (e.g. "Glass" on
+// macOS, "bell" on Linux) when sound isn't a path
+func playSound(sound string) error {
+	isPath := strings.ContainsRune(sound, filepath.Separator)
+
+	switch runtime.GOOS {
+	case "darwin":
+		if !isPath {
+			sound = "/System/Library/Sounds/" + sound + ".aiff"
+		}
+		return exec.Command("afplay", sound).Run()
+	case "linux":
+		if !isPath {
+			sound = "/usr/share/sounds/freedesktop/stereo/" + sound + ".oga"
+		}
+		return exec.Command("paplay", sound).Run()
+	case "windows":
+		if !isPath {
+			sound = `C:\Windows\Media\` + sound + ".wav"
+		}
+		script := fmt.Sprintf("(New-Object Media.SoundPlayer %s).PlaySync()", powerShellQuote(sound))
+		return exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).Run()
+	default:
+		return fmt.Errorf("sound alerts not supported on %s", runtime.GOOS)
+	}
+}
+
+// Helper function to play a sound in the background, since playback blocks
+// until the sound ends and this is called from the fetch goroutine
+func playSoundAsync(sound string) {
+	go func() {
+		if err := playSound(sound); err != nil {
+			log.Printf("Warning: Failed to play sound: %v\n", err)
+		}
+	}()
+}
+
+// sendNotification shows a desktop notification using the platform's native tool
+func sendNotification(title, message string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s",
+			appleScriptQuote(message), appleScriptQuote(title))
+		return exec.Command("osascript", "-e", script).Run()
+	case "linux":
+		return exec.Command("notify-send", title, message).Run()
+	case "windows":
+		script := fmt.Sprintf(windowsToastScript, powerShellQuote(title), powerShellQuote(message))
+		return exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).Run()
+	default:
+		return fmt.Errorf("notifications not supported on %s", runtime.GOOS)
+	}
+}
+
+// Helper function to send a notification in the background, since starting
+// the platform's tool (PowerShell in particular) is slow and this is called
+// from the fetch goroutine
+func sendNotificationAsync(message string) {
+	go func() {
+		if err := sendNotification("Claude Monitor Lite", message); err != nil {
+			log.Printf("Warning: Failed to send notification: %v\n", err)
+		}
+	}()
+}
+
+// windowsToastScript shows a toast notification from PowerShell; it takes the
+// quoted title and message
+const windowsToastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode(%s)) > $null
+$text.Item(1).AppendChild($template.CreateTextNode(%s)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('Claude Monitor Lite').Show([Windows.UI.Notifications.ToastNotification]::new($template))`
+
+// powerShellQuote returns s as a single-quoted PowerShell string literal,
+// where nothing is expanded and only ' needs escaping
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// appleScriptQuote returns s as a quoted AppleScript string literal
+func appleScriptQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
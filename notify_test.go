@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+func TestUsageNotifierCooldown(t *testing.T) {
+	n := &usageNotifier{lastNotified: make(map[string]time.Time)}
+	thresholds := []float64{80, 90}
+	cooldown := time.Hour
+	start := time.Date(2025, 6, 12, 12, 0, 0, 0, time.UTC)
+
+	at := func(utilization float64) *claude.UsageLimits {
+		return &claude.UsageLimits{FiveHour: &claude.UsageLimit{Utilization: utilization}}
+	}
+
+	steps := []struct {
+		name        string
+		utilization float64
+		offset      time.Duration
+		wantCount   int
+	}{
+		{"below thresholds", 50, 0, 0},
+		{"crosses 90 fires once", 92, time.Minute, 1},
+		{"hovering within cooldown", 94, 2 * time.Minute, 0},
+		{"still hovering", 91, 30 * time.Minute, 0},
+		{"cooldown expired", 93, 62 * time.Minute, 1},
+		{"drops after reset", 5, 70 * time.Minute, 0},
+		{"crosses 80 again after reset", 81, 80 * time.Minute, 1},
+	}
+
+	for _, step := range steps {
+		got := n.check(at(step.utilization), thresholds, cooldown, start.Add(step.offset))
+		if len(got) != step.wantCount {
+			t.Errorf("%s: got %d notifications %v, want %d", step.name, len(got), got, step.wantCount)
+		}
+	}
+}
+
+func TestUsageNotifierCheckCritical(t *testing.T) {
+	setTestConfig(t, func(c *Config) {
+		c.ColorThresholds = map[string]ColorThresholds{"seven_day": {Red: 60}}
+	})
+	n := &usageNotifier{lastNotified: make(map[string]time.Time)}
+	start := time.Date(2025, 6, 12, 12, 0, 0, 0, time.UTC)
+
+	// 65% is below the global red but above the weekly override
+	limits := &claude.UsageLimits{
+		FiveHour: &claude.UsageLimit{Utilization: 65},
+		SevenDay: &claude.UsageLimit{Utilization: 65},
+	}
+	if !n.checkCritical(limits, time.Hour, start) {
+		t.Error("checkCritical() with the weekly limit over its red override = false, want true")
+	}
+	if n.checkCritical(limits, time.Hour, start.Add(time.Minute)) {
+		t.Error("checkCritical() within the cooldown = true, want false")
+	}
+
+	limits.SevenDay.Utilization = 50
+	if n.checkCritical(limits, time.Hour, start.Add(2*time.Minute)) {
+		t.Error("checkCritical() with every limit below its red = true, want false")
+	}
+}
+
+func TestUsageNotifierResetSoon(t *testing.T) {
+	n := &usageNotifier{lastNotified: make(map[string]time.Time)}
+	before, cooldown := 15*time.Minute, 5*time.Minute
+	start := time.Date(2025, 6, 12, 12, 0, 0, 0, time.UTC)
+	firstReset := start.Add(30 * time.Minute)
+	nextReset := firstReset.Add(5 * time.Hour)
+
+	steps := []struct {
+		name   string
+		reset  time.Time
+		offset time.Duration
+		want   bool
+	}{
+		{"too early", firstReset, 0, false},
+		{"inside lead time fires", firstReset, 16 * time.Minute, true},
+		{"after cooldown, same window", firstReset, 25 * time.Minute, false},
+		{"new window too early", nextReset, 35 * time.Minute, false},
+		{"re-armed for the next window", nextReset, 5*time.Hour + 20*time.Minute, true},
+		{"no reset time", time.Time{}, 5*time.Hour + 25*time.Minute, false},
+	}
+
+	for _, step := range steps {
+		limit := &claude.UsageLimit{Utilization: 50, ResetsAtTime: step.reset}
+		if got := n.checkResetSoon(limit, before, cooldown, start.Add(step.offset)); got != step.want {
+			t.Errorf("%s: checkResetSoon() = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestAppleScriptQuote(t *testing.T) {
+	if got, want := appleScriptQuote(`say "hi" \ bye`), `"say \"hi\" \\ bye"`; got != want {
+		t.Errorf("appleScriptQuote() = %s, want %s", got, want)
+	}
+}
+
+func TestPowerShellQuote(t *testing.T) {
+	if got, want := powerShellQuote(`it's $HOME "here"`), `'it''s $HOME "here"'`; got != want {
+		t.Errorf("powerShellQuote() = %s, want %s", got, want)
+	}
+}
+
+func TestSnoozedUntil(t *testing.T) {
+	defer setSnooze(time.Time{})
+	now := time.Date(2025, 6, 12, 14, 0, 0, 0, time.UTC)
+
+	setSnooze(now.Add(time.Hour))
+	if until, ok := snoozedUntil(now); !ok || !until.Equal(now.Add(time.Hour)) {
+		t.Errorf("snoozedUntil() = %v, %v, want %v, true", until, ok, now.Add(time.Hour))
+	}
+	if _, ok := snoozedUntil(now.Add(time.Hour)); ok {
+		t.Error("snoozedUntil() still active once the snooze ends")
+	}
+
+	setSnooze(time.Time{})
+	if _, ok := snoozedUntil(now); ok {
+		t.Error("snoozedUntil() active after clearing")
+	}
+}
+
+func TestDetectResets(t *testing.T) {
+	now := time.Date(2025, 6, 12, 14, 0, 0, 0, time.UTC)
+	oldReset := now.Add(-time.Minute)
+	newReset := now.Add(5 * time.Hour)
+
+	session := func(utilization float64, reset time.Time) *claude.UsageLimits {
+		return &claude.UsageLimits{FiveHour: &claude.UsageLimit{Utilization: utilization, ResetsAtTime: reset}}
+	}
+
+	tests := []struct {
+		name     string
+		previous *claude.UsageLimits
+		current  *claude.UsageLimits
+		want     int
+	}{
+		{"window rolled to a new one", session(60, oldReset), session(2, newReset), 1},
+		{"window ended with no new session", session(60, oldReset), session(0, time.Time{}), 1},
+		{"sharp drop", session(85, newReset), session(10, newReset), 1},
+		{"still counting up", session(40, newReset), session(45, newReset), 0},
+		{"small dip", session(40, newReset), session(35, newReset), 0},
+		{"reset time not reached", session(40, newReset), session(40, newReset.Add(time.Minute)), 0},
+		{"was unused", session(0, oldReset), session(0, time.Time{}), 0},
+		{"limit missing", session(60, oldReset), &claude.UsageLimits{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectResets(tt.previous, tt.current, now); len(got) != tt.want {
+				t.Errorf("detectResets() = %v, want %d reset(s)", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifiableLimits(t *testing.T) {
+	limits := &claude.UsageLimits{
+		FiveHour:     &claude.UsageLimit{Utilization: 85},
+		SevenDay:     &claude.UsageLimit{Utilization: 95},
+		SevenDayOpus: &claude.UsageLimit{Utilization: 95},
+	}
+
+	if got := notifiableLimits(limits); got != limits {
+		t.Errorf("notifiableLimits() with no setting = %+v, want the limits unchanged", got)
+	}
+	if notifiableLimits(nil) != nil {
+		t.Error("notifiableLimits(nil) != nil")
+	}
+
+	setTestConfig(t, func(c *Config) { c.NotifyLimits = []string{"five_hour"} })
+	got := notifiableLimits(limits)
+	if got.FiveHour == nil || got.SevenDay != nil || got.SevenDayOpus != nil {
+		t.Errorf("notifiableLimits() = %+v, want only the five-hour limit", got)
+	}
+	if limits.SevenDay == nil {
+		t.Error("notifiableLimits() modified its argument")
+	}
+
+	n := &usageNotifier{lastNotified: make(map[string]time.Time)}
+	if messages := n.check(got, []float64{80}, time.Hour, time.Now()); len(messages) != 1 {
+		t.Errorf("check() = %v, want one five-hour notification", messages)
+	}
+}
+
+func TestFailureCounter(t *testing.T) {
+	f := &failureCounter{}
+	failure := errors.New("network down")
+
+	var streaks []int
+	for _, err := range []error{failure, failure, failure, nil, failure} {
+		streaks = append(streaks, f.record(err))
+	}
+	if want := []int{1, 2, 3, 0, 1}; !slices.Equal(streaks, want) {
+		t.Errorf("record() streaks = %v, want %v", streaks, want)
+	}
+	if got := f.streak(); got != 1 {
+		t.Errorf("streak() = %d, want 1", got)
+	}
+}
@@ -0,0 +1,209 @@
+// paths.go - Where the config and state files live
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	dataDirEnv = "CLAUDE_MONITOR_DATA_DIR"
+	appDirName = "claude-monitor-lite"
+
+	dataDirPermissions = 0700 // Owner only
+
+	configFileName = "config.json"
+	stateFileBase  = "monitor" // monitor.pid, monitor.sock, monitor.log, ...
+
+	// Dotfile prefix used in the home directory before the data directory existed
+	legacyFilePrefix = ".claude-monitor-lite"
+)
+
+// Files moved out of the home directory on first run, as suffixes of the
+// legacy prefix. The config goes last: it marks the migration as done.
+var legacyStateSuffixes = []string{".history.jsonl", ".log", ".log.old"}
+
+// ResolveConfigPath returns the config file path, honoring CLAUDE_MONITOR_CONFIG
+// (also set by the --config flag), then CLAUDE_MONITOR_DATA_DIR, then the
+// platform config directory. It fails rather than fall back to a path
+// relative to the working directory.
+func ResolveConfigPath() (string, error) {
+	if path := os.Getenv(configPathEnv); path != "" {
+		if !filepath.IsAbs(path) {
+			return "", fmt.Errorf("%s must be an absolute path, got %q", configPathEnv, path)
+		}
+		return path, nil
+	}
+
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, configFileName)
+
+	// Until its files are migrated, an existing install keeps its dotfiles
+	if legacy := legacyConfigPath(); legacy != "" && !fileExists(path) && fileExists(legacy) {
+		return legacy, nil
+	}
+	return path, nil
+}
+
+// GetConfigPath returns the config file path, or "" if it can't be resolved
+func GetConfigPath() string {
+	path, _ := ResolveConfigPath()
+	return path
+}
+
+// ResolveStatePrefix returns the path shared by the PID, socket, history and
+// log files, which append their own extension to it
+func ResolveStatePrefix() (string, error) {
+	configPath, err := ResolveConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	// An explicit config keeps its state next to it so separate configs
+	// never collide; so does an install that hasn't been migrated yet
+	if os.Getenv(configPathEnv) != "" || configPath == legacyConfigPath() {
+		return strings.TrimSuffix(configPath, ".json"), nil
+	}
+
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, stateFileBase), nil
+}
+
+// configDir returns the directory for config.json: CLAUDE_MONITOR_DATA_DIR,
+// else $XDG_CONFIG_HOME (~/.config) on Linux, ~/Library/Application Support
+// on macOS and %AppData% on Windows
+func configDir() (string, error) {
+	if dir, err := dataDirOverride(); dir != "" || err != nil {
+		return dir, err
+	}
+
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine config directory; set %s or %s", dataDirEnv, configPathEnv)
+	}
+	return filepath.Join(base, appDirName), nil
+}
+
+// stateDir returns the directory for the PID, socket, history and log files.
+// Linux keeps them under $XDG_DATA_HOME (~/.local/share); elsewhere they
+// share the config directory.
+func stateDir() (string, error) {
+	if dir, err := dataDirOverride(); dir != "" || err != nil {
+		return dir, err
+	}
+	if runtime.GOOS != "linux" {
+		return configDir()
+	}
+
+	base := os.Getenv("XDG_DATA_HOME")
+	if !filepath.IsAbs(base) {
+		// The XDG spec says to ignore relative paths
+		homeDir, err := os.UserHomeDir()
+		if err != nil || homeDir == "" {
+			return "", fmt.Errorf("cannot determine home directory; set %s or %s", dataDirEnv, configPathEnv)
+		}
+		base = filepath.Join(homeDir, ".local", "share")
+	}
+	return filepath.Join(base, appDirName), nil
+}
+
+// dataDirOverride returns CLAUDE_MONITOR_DATA_DIR, or "" if it isn't set
+func dataDirOverride() (string, error) {
+	dir := os.Getenv(dataDirEnv)
+	if dir != "" && !filepath.IsAbs(dir) {
+		return "", fmt.Errorf("%s must be an absolute path, got %q", dataDirEnv, dir)
+	}
+	return dir, nil
+}
+
+// legacyConfigPath returns the old ~/.claude-monitor-lite.json location,
+// or "" if the home directory is unknown
+func legacyConfigPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil || homeDir == "" {
+		return ""
+	}
+	return filepath.Join(homeDir, legacyFilePrefix+".json")
+}
+
+// ensureDataDirs creates the directories holding the config and state files
+func ensureDataDirs(configPath, statePrefix string) error {
+	for _, dir := range []string{filepath.Dir(configPath), filepath.Dir(statePrefix)} {
+		if err := os.MkdirAll(dir, dataDirPermissions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateLegacyFiles moves dotfiles from the home directory into the data
+// directory. It waits while a monitor started from the old layout is still
+// running, since moving its PID file would leave it impossible to stop.
+func migrateLegacyFiles() {
+	if os.Getenv(configPathEnv) != "" {
+		return
+	}
+
+	legacyConfig := legacyConfigPath()
+	if legacyConfig == "" || GetConfigPath() != legacyConfig {
+		return
+	}
+	legacyPrefix := strings.TrimSuffix(legacyConfig, ".json")
+	if pidFileAlive(legacyPrefix + ".pid") {
+		return
+	}
+
+	dir, err := configDir()
+	if err != nil {
+		return
+	}
+	configPath := filepath.Join(dir, configFileName)
+	state, err := stateDir()
+	if err != nil {
+		return
+	}
+	statePrefix := filepath.Join(state, stateFileBase)
+	if err := ensureDataDirs(configPath, statePrefix); err != nil {
+		log.Printf("Warning: Failed to create data directory: %v\n", err)
+		return
+	}
+
+	for _, suffix := range legacyStateSuffixes {
+		moveLegacyFile(legacyPrefix+suffix, statePrefix+suffix)
+	}
+	backups, _ := filepath.Glob(legacyConfig + ".bak-*")
+	for _, backup := range backups {
+		moveLegacyFile(backup, configPath+strings.TrimPrefix(backup, legacyConfig))
+	}
+	if moveLegacyFile(legacyConfig, configPath) {
+		infof("Moved settings from %s to %s\n", legacyConfig, dir)
+	}
+}
+
+// moveLegacyFile renames from to to, reporting whether it moved anything
+func moveLegacyFile(from, to string) bool {
+	if err := os.Rename(from, to); err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: Failed to move %s: %v\n", from, err)
+		}
+		return false
+	}
+	return true
+}
+
+// Helper function to check whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
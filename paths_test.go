@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupHome points the home and XDG directories at a temp dir
+func setupHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv(configPathEnv, "")
+	t.Setenv(dataDirEnv, "")
+	return home
+}
+
+func TestResolvePathsDataDirOverride(t *testing.T) {
+	setupHome(t)
+	dir := t.TempDir()
+	t.Setenv(dataDirEnv, dir)
+
+	if got, err := ResolveConfigPath(); err != nil || got != filepath.Join(dir, "config.json") {
+		t.Errorf("ResolveConfigPath() = %q, %v, want config.json in %s", got, err, dir)
+	}
+	if got, err := ResolveStatePrefix(); err != nil || got != filepath.Join(dir, "monitor") {
+		t.Errorf("ResolveStatePrefix() = %q, %v, want monitor in %s", got, err, dir)
+	}
+
+	t.Setenv(dataDirEnv, "relative/dir")
+	if _, err := ResolveConfigPath(); err == nil {
+		t.Error("ResolveConfigPath() with a relative data dir: want error")
+	}
+}
+
+func TestResolveStatePrefixExplicitConfig(t *testing.T) {
+	setupHome(t)
+	path := filepath.Join(t.TempDir(), "work.json")
+	t.Setenv(configPathEnv, path)
+
+	want := filepath.Join(filepath.Dir(path), "work")
+	if got, err := ResolveStatePrefix(); err != nil || got != want {
+		t.Errorf("ResolveStatePrefix() = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestMigrateLegacyFiles(t *testing.T) {
+	home := setupHome(t)
+	dataDir := filepath.Join(home, "data")
+	t.Setenv(dataDirEnv, dataDir)
+
+	legacy := filepath.Join(home, legacyFilePrefix)
+	files := map[string]string{
+		legacy + ".json":                     `{"sessionKey": "sk-test"}`,
+		legacy + ".history.jsonl":            "{}\n",
+		legacy + ".json.bak-20250101-000000": "{}",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), configFilePermissions); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Before migrating, the existing install keeps working from its dotfiles
+	if got := GetConfigPath(); got != legacy+".json" {
+		t.Fatalf("GetConfigPath() before migration = %q, want legacy path", got)
+	}
+
+	migrateLegacyFiles()
+
+	for _, path := range []string{
+		filepath.Join(dataDir, "config.json"),
+		filepath.Join(dataDir, "monitor.history.jsonl"),
+		filepath.Join(dataDir, "config.json.bak-20250101-000000"),
+	} {
+		if !fileExists(path) {
+			t.Errorf("%s missing after migration", path)
+		}
+	}
+	for path := range files {
+		if fileExists(path) {
+			t.Errorf("%s still present after migration", path)
+		}
+	}
+	if got := GetConfigPath(); got != filepath.Join(dataDir, "config.json") {
+		t.Errorf("GetConfigPath() after migration = %q", got)
+	}
+	if config := LoadConfig(); config.SessionKey != "sk-test" {
+		t.Errorf("SessionKey after migration = %q, want sk-test", config.SessionKey)
+	}
+}
@@ -0,0 +1,131 @@
+// polling.go - Refresh interval selection
+
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+const (
+	defaultMinRefreshInterval = refreshInterval
+	defaultMaxRefreshInterval = 5 * time.Minute
+
+	// Adaptive polling heuristic bounds on the five-hour limit
+	adaptiveLowUtilization  = 50.0
+	adaptiveHighUtilization = 80.0
+	adaptiveResetSoon       = 15 * time.Minute
+
+	// Sleep detection: a check arriving this much later than scheduled
+	// means the machine was asleep in between
+	wakeCheckInterval = 15 * time.Second
+	wakeGapThreshold  = time.Minute
+)
+
+// watchForWake sends on the returned channel each time the machine wakes
+// from sleep, until ctx is done. Timers don't advance during sleep on macOS,
+// so the refresh ticker alone would only notice a full interval later.
+func watchForWake(ctx context.Context) <-chan struct{} {
+	woke := make(chan struct{}, 1)
+	go func() {
+		ticker := time.NewTicker(wakeCheckInterval)
+		defer ticker.Stop()
+
+		last := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if sleptBetween(last, now, wakeCheckInterval) {
+					select {
+					case woke <- struct{}{}:
+					default:
+						// A wake is already pending
+					}
+				}
+				last = now
+			}
+		}
+	}()
+	return woke
+}
+
+// Helper function to check whether the wall clock moved further between two
+// checks than the interval allows, i.e. the machine slept in between.
+// Round(0) drops the monotonic reading, which stops during sleep.
+func sleptBetween(last, now time.Time, interval time.Duration) bool {
+	return now.Round(0).Sub(last.Round(0)) > interval+wakeGapThreshold
+}
+
+// handleWake refreshes straight away after sleep, on fresh connections
+func handleWake() {
+	log.Println("Woke from sleep, refreshing")
+	if client := claudeClient.Load(); client != nil {
+		client.CloseIdleConnections()
+	}
+	startUpdate()
+}
+
+// nextRefreshInterval returns how long to wait before the next fetch
+func nextRefreshInterval() time.Duration {
+	config := currentConfig()
+	now := time.Now()
+	if quiet := config.QuietHours; quiet != nil && quiet.RefreshSeconds > 0 && inQuietHours(now) {
+		// Never faster than usual, and back to normal when quiet hours end
+		interval := max(time.Duration(quiet.RefreshSeconds)*time.Second, refreshInterval)
+		return min(interval, max(quiet.endsIn(now), refreshInterval))
+	}
+	if !config.AdaptivePolling {
+		return refreshInterval
+	}
+
+	minInterval := defaultMinRefreshInterval
+	if config.MinRefreshSeconds > 0 {
+		minInterval = time.Duration(config.MinRefreshSeconds) * time.Second
+	}
+	maxInterval := defaultMaxRefreshInterval
+	if config.MaxRefreshSeconds > 0 {
+		maxInterval = time.Duration(config.MaxRefreshSeconds) * time.Second
+	}
+
+	limitsMutex.RLock()
+	cached := lastLimits
+	limitsMutex.RUnlock()
+
+	return adaptiveInterval(cached, minInterval, maxInterval, now)
+}
+
+// adaptiveInterval picks a polling interval from the five-hour limit:
+//   - at or above 80%, or within 15 minutes of a reset: poll at min
+//   - below 50% (or no active session): poll at max
+//   - in between: scale linearly from max down to min
+//
+// Without data it polls at min so the first values arrive quickly.
+func adaptiveInterval(limits *claude.UsageLimits, minInterval, maxInterval time.Duration, now time.Time) time.Duration {
+	if maxInterval < minInterval {
+		maxInterval = minInterval
+	}
+	if limits == nil || limits.FiveHour == nil {
+		return minInterval
+	}
+
+	limit := limits.FiveHour
+	if !limit.ResetsAtTime.IsZero() && limit.ResetsAtTime.Sub(now) < adaptiveResetSoon {
+		return minInterval
+	}
+
+	switch {
+	case limit.Utilization >= adaptiveHighUtilization:
+		return minInterval
+	case limit.Utilization < adaptiveLowUtilization:
+		return maxInterval
+	}
+
+	// Linear scale between the two utilization bounds
+	fraction := (limit.Utilization - adaptiveLowUtilization) / (adaptiveHighUtilization - adaptiveLowUtilization)
+	return maxInterval - time.Duration(fraction*float64(maxInterval-minInterval))
+}
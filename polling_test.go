@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+func TestAdaptiveInterval(t *testing.T) {
+	now := time.Date(2025, 6, 12, 12, 0, 0, 0, time.UTC)
+	minInterval := 30 * time.Second
+	maxInterval := 5 * time.Minute
+
+	limitAt := func(utilization float64, untilReset time.Duration) *claude.UsageLimits {
+		limit := &claude.UsageLimit{Utilization: utilization}
+		if untilReset > 0 {
+			limit.ResetsAtTime = now.Add(untilReset)
+		}
+		return &claude.UsageLimits{FiveHour: limit}
+	}
+
+	tests := []struct {
+		name   string
+		limits *claude.UsageLimits
+		want   time.Duration
+	}{
+		{"no data", nil, minInterval},
+		{"no five-hour limit", &claude.UsageLimits{}, minInterval},
+		{"idle", limitAt(0, 0), maxInterval},
+		{"low usage far from reset", limitAt(20, 3*time.Hour), maxInterval},
+		{"low usage near reset", limitAt(20, 10*time.Minute), minInterval},
+		{"midpoint", limitAt(65, 3*time.Hour), 165 * time.Second},
+		{"high usage", limitAt(85, 3*time.Hour), minInterval},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := adaptiveInterval(tt.limits, minInterval, maxInterval, now); got != tt.want {
+				t.Errorf("adaptiveInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSleptBetween(t *testing.T) {
+	last := time.Now()
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    bool
+	}{
+		{"on schedule", wakeCheckInterval, false},
+		{"slightly late", wakeCheckInterval + 5*time.Second, false},
+		{"woke from sleep", 2 * time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		if got := sleptBetween(last, last.Add(tt.elapsed), wakeCheckInterval); got != tt.want {
+			t.Errorf("%s: sleptBetween() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// quiet.go - Quiet hours: no alerts, and optionally slower polling, overnight
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// QuietHours is a daily time range, e.g. 22:00 to 07:00, with no
+// notifications. The menu bar keeps showing the latest data.
+type QuietHours struct {
+	Start          string `json:"start"`                    // "HH:MM", inclusive
+	End            string `json:"end"`                      // "HH:MM", exclusive; before Start crosses midnight
+	Timezone       string `json:"timezone,omitempty"`       // IANA name, e.g. "Europe/Berlin"; empty for the system zone
+	RefreshSeconds int    `json:"refreshSeconds,omitempty"` // Polling interval while quiet; 0 keeps the usual one
+
+	// Parsed by validateQuietHours
+	start, end int // Minutes since midnight
+	location   *time.Location
+}
+
+// Helper function to parse "HH:MM" into minutes since midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time like 22:00", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// validateQuietHours parses the quiet hours, disabling them with a warning
+// if they can't be used
+func validateQuietHours(config *Config) {
+	quiet := config.QuietHours
+	if quiet == nil {
+		return
+	}
+
+	err := func() error {
+		var err error
+		if quiet.start, err = parseClock(quiet.Start); err != nil {
+			return fmt.Errorf("start: %w", err)
+		}
+		if quiet.end, err = parseClock(quiet.End); err != nil {
+			return fmt.Errorf("end: %w", err)
+		}
+		if quiet.start == quiet.end {
+			return fmt.Errorf("start and end are both %s", quiet.Start)
+		}
+		quiet.location = time.Local
+		if quiet.Timezone != "" {
+			if quiet.location, err = time.LoadLocation(quiet.Timezone); err != nil {
+				return fmt.Errorf("timezone: %w", err)
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		log.Printf("Warning: quietHours %v, ignoring quiet hours\n", err)
+		config.QuietHours = nil
+	}
+}
+
+// contains reports whether t falls within the quiet hours
+func (q *QuietHours) contains(t time.Time) bool {
+	local := t.In(q.location)
+	minute := local.Hour()*60 + local.Minute()
+	if q.start < q.end {
+		return minute >= q.start && minute < q.end
+	}
+	// Crosses midnight, e.g. 22:00-07:00
+	return minute >= q.start || minute < q.end
+}
+
+// endsIn returns how long until the quiet hours that contain t end
+func (q *QuietHours) endsIn(t time.Time) time.Duration {
+	local := t.In(q.location)
+	end := time.Date(local.Year(), local.Month(), local.Day(), q.end/60, q.end%60, 0, 0, q.location)
+	if !end.After(local) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end.Sub(local)
+}
+
+// Helper function to check whether now is within the configured quiet hours
+func inQuietHours(now time.Time) bool {
+	quiet := currentConfig().QuietHours
+	return quiet != nil && quiet.location != nil && quiet.contains(now)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHoursContains(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, 6, 12, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		quiet    QuietHours
+		t        time.Time
+		want     bool
+		wantEnds time.Duration
+	}{
+		{"same day, inside", QuietHours{Start: "12:00", End: "14:00", Timezone: "UTC"}, at(13, 30), true, 30 * time.Minute},
+		{"same day, end is exclusive", QuietHours{Start: "12:00", End: "14:00", Timezone: "UTC"}, at(14, 0), false, 0},
+		{"crosses midnight, evening", QuietHours{Start: "22:00", End: "07:00", Timezone: "UTC"}, at(23, 0), true, 8 * time.Hour},
+		{"crosses midnight, morning", QuietHours{Start: "22:00", End: "07:00", Timezone: "UTC"}, at(6, 59), true, time.Minute},
+		{"crosses midnight, daytime", QuietHours{Start: "22:00", End: "07:00", Timezone: "UTC"}, at(12, 0), false, 0},
+		// 14:00 UTC is 23:00 in Tokyo
+		{"configured timezone", QuietHours{Start: "22:00", End: "07:00", Timezone: "Asia/Tokyo"}, at(14, 0), true, 8 * time.Hour},
+		{"configured timezone, daytime", QuietHours{Start: "22:00", End: "07:00", Timezone: "Asia/Tokyo"}, at(3, 0), false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{QuietHours: &tt.quiet}
+			validateQuietHours(&config)
+			if config.QuietHours == nil {
+				t.Fatal("validateQuietHours() rejected valid quiet hours")
+			}
+			if got := config.QuietHours.contains(tt.t); got != tt.want {
+				t.Errorf("contains(%s) = %v, want %v", tt.t.In(tokyo).Format("15:04 MST"), got, tt.want)
+			}
+			if tt.want {
+				if got := config.QuietHours.endsIn(tt.t); got != tt.wantEnds {
+					t.Errorf("endsIn() = %v, want %v", got, tt.wantEnds)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateQuietHoursRejectsInvalid(t *testing.T) {
+	for _, quiet := range []QuietHours{
+		{Start: "10pm", End: "07:00"},
+		{Start: "22:00", End: "25:00"},
+		{Start: "22:00", End: "22:00"},
+		{Start: "22:00", End: "07:00", Timezone: "Mars/Olympus_Mons"},
+	} {
+		config := Config{QuietHours: &quiet}
+		validateQuietHours(&config)
+		if config.QuietHours != nil {
+			t.Errorf("validateQuietHours(%+v) kept invalid quiet hours", quiet)
+		}
+	}
+}
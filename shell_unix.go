@@ -0,0 +1,13 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"os/exec"
+)
+
+// Helper function to run a user-supplied command line through the shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
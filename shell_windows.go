@@ -0,0 +1,18 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// Helper function to run a user-supplied command line through cmd.exe. The
+// line is passed as is: Go's argument quoting would escape its quotes, which
+// cmd.exe doesn't understand. /S strips only the outer pair added here.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "cmd")
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `/S /C "` + command + `"`}
+	return cmd
+}
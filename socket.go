@@ -0,0 +1,84 @@
+// socket.go - Local Unix socket serving cached usage to other tools
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+const (
+	socketFilePermissions = 0600 // Owner read/write only
+)
+
+var socketServer *http.Server
+
+// socketResponse is the JSON document served on the socket
+type socketResponse struct {
+	*claude.UsageLimits
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+// startSocketServer serves lastLimits as JSON on a Unix domain socket.
+// Query with: curl --unix-socket <data dir>/monitor.sock http://localhost/
+func startSocketServer() {
+	// Remove a stale socket left behind by a crashed daemon
+	if err := os.Remove(socketFile); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: Failed to remove stale socket: %v\n", err)
+		return
+	}
+
+	listener, err := listenUnixPrivate(socketFile)
+	if err != nil {
+		log.Printf("Warning: Failed to listen on socket: %v\n", err)
+		return
+	}
+
+	if err := os.Chmod(socketFile, socketFilePermissions); err != nil {
+		log.Printf("Warning: Failed to restrict socket permissions: %v\n", err)
+		listener.Close()
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handleSocketRequest)
+	socketServer = &http.Server{Handler: mux}
+
+	go func() {
+		if err := socketServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Warning: Socket server stopped: %v\n", err)
+		}
+	}()
+}
+
+func handleSocketRequest(w http.ResponseWriter, r *http.Request) {
+	limitsMutex.RLock()
+	cached := lastLimits
+	limitsMutex.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if cached == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "no usage data yet"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(socketResponse{UsageLimits: cached, LastUpdated: cached.LastUpdated})
+}
+
+// stopSocketServer closes the server and removes the socket file
+func stopSocketServer() {
+	if socketServer == nil {
+		return
+	}
+	socketServer.Close()
+	if err := os.Remove(socketFile); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: Failed to remove socket file: %v\n", err)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package main
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenUnixPrivate listens on a Unix socket that only the owner can use
+// from the moment it exists. The socket may sit outside the private data
+// directory (e.g. next to a --config file), so a Chmod after Listen would
+// leave a window. The umask is process-wide, but only ever tightened here.
+func listenUnixPrivate(path string) (net.Listener, error) {
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
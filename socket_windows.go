@@ -0,0 +1,11 @@
+//go:build windows
+
+package main
+
+import "net"
+
+// listenUnixPrivate listens on a Unix socket. Windows has no umask; the
+// socket is restricted by the Chmod after it, and its directory's ACL.
+func listenUnixPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,137 @@
+// update.go - Checking GitHub for a newer release
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/wickes1/claude-monitor-lite/claude"
+)
+
+const latestReleaseURL = "https://api.github.com/repos/wickes1/claude-monitor-lite/releases/latest"
+
+// Set at build time via -ldflags "-X main.version=..."
+var version = "dev"
+
+// releaseInfo is the part of the GitHub release response we use
+type releaseInfo struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+}
+
+// fetchLatestRelease asks GitHub for the latest release, going through the
+// same proxy and timeout settings as the usage client
+func fetchLatestRelease(ctx context.Context) (*releaseInfo, error) {
+	config := currentConfig()
+	req, err := http.NewRequestWithContext(ctx, "GET", latestReleaseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := claude.NewHTTPClient(claude.HTTPOptions{
+		DisableKeepAlives: config.DisableKeepAlives,
+		TLSConfig:         loadTLSConfig(),
+		IPVersion:         claude.IPVersion(config.IPVersion),
+	}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for updates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to check for updates (status %d)", resp.StatusCode)
+	}
+
+	var release releaseInfo
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+	return &release, nil
+}
+
+// isNewerVersion reports whether latest is a higher version than current.
+// Both may have a "v" prefix; a non-numeric current version (e.g. "dev")
+// is never considered outdated.
+func isNewerVersion(latest, current string) bool {
+	latestParts, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	currentParts, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+
+	for i := range max(len(latestParts), len(currentParts)) {
+		var l, c int
+		if i < len(latestParts) {
+			l = latestParts[i]
+		}
+		if i < len(currentParts) {
+			c = currentParts[i]
+		}
+		if l != c {
+			return l > c
+		}
+	}
+	return false
+}
+
+// parseVersion splits "v1.2.3" (ignoring any "-suffix") into its numbers
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "-")
+
+	var parts []int
+	for field := range strings.SplitSeq(v, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
+
+// checkForUpdate shows the Update Available menu item if a newer release
+// exists. Development builds never check.
+func checkForUpdate() {
+	if _, ok := parseVersion(version); !ok {
+		return
+	}
+
+	release, err := fetchLatestRelease(appCtx)
+	if err != nil {
+		log.Printf("Warning: %v\n", err)
+		return
+	}
+	if isNewerVersion(release.TagName, version) {
+		updateURL.Store(&release.HTMLURL)
+		mUpdate.SetTitle("Update Available: " + release.TagName)
+		mUpdate.Show()
+	}
+}
+
+func handleUpdateCheck() {
+	release, err := fetchLatestRelease(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitNetworkError)
+	}
+
+	infof("Current version: %s\n", version)
+	infof("Latest release:  %s\n", release.TagName)
+	if isNewerVersion(release.TagName, version) {
+		infof("Update available: %s\n", release.HTMLURL)
+	} else {
+		info("You're up to date.")
+	}
+}
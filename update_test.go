@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestIsNewerVersion(t *testing.T) {
+	tests := []struct {
+		latest  string
+		current string
+		want    bool
+	}{
+		{"v1.2.0", "1.1.9", true},
+		{"v1.10.0", "1.9.0", true},
+		{"v2.0", "1.9.9", true},
+		{"v1.2.0", "1.2.0", false},
+		{"v1.2.0", "v1.2.0-next", false},
+		{"v1.1.0", "1.2.0", false},
+		{"v1.2.1", "dev", false},
+		{"nightly", "1.2.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.latest+"_vs_"+tt.current, func(t *testing.T) {
+			if got := isNewerVersion(tt.latest, tt.current); got != tt.want {
+				t.Errorf("isNewerVersion(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+			}
+		})
+	}
+}